@@ -0,0 +1,237 @@
+package canvas
+
+import (
+	"strconv"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+// FromANSI builds a canvas from text with terminal escape codes, such as a
+// saved .ans file. SGR codes set the style of the cells that follow: 16,
+// 256 and truecolor foreground and background, the attributes of Attr and
+// their resets. Other escape sequences and control characters are dropped,
+// tabs stop every 8 columns, and a SAUCE record after ^Z is ignored. Bytes
+// outside ASCII are kept as they are, so UTF-8 text survives although each
+// of its bytes takes a cell.
+func FromANSI(s string) *Canvas {
+	if i := strings.IndexByte(s, 0x1a); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+
+	c := &Canvas{}
+	var style Style
+	var row Row
+	band := 0
+	endRow := func() {
+		if len(row.Cells) == 0 {
+			row.Band = -1
+			band = 0
+		} else {
+			row.Band = band % BandHeight
+			band++
+		}
+		c.Rows = append(c.Rows, row)
+		row = Row{}
+	}
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == 0x1b:
+			i = skipEscape(s, i, &style)
+		case ch == '\n':
+			endRow()
+		case ch == '\t':
+			for {
+				row.Cells = append(row.Cells, Cell{Ch: ' ', Style: style, Src: -1})
+				if len(row.Cells)%8 == 0 {
+					break
+				}
+			}
+		case ch < 0x20 || ch == 0x7f:
+			// other control characters draw nothing
+		default:
+			row.Cells = append(row.Cells, Cell{Ch: ch, Style: style, Src: -1})
+		}
+	}
+	if len(s) > 0 {
+		endRow()
+	}
+	return c
+}
+
+// skipEscape reads the escape sequence starting at s[i], applies it to
+// style when it is an SGR code, and returns the index of its last byte
+func skipEscape(s string, i int, style *Style) int {
+	if i+1 >= len(s) {
+		return i
+	}
+	switch s[i+1] {
+	case '[': // CSI: parameters, then a final byte in @–~
+		j := i + 2
+		for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e) {
+			j++
+		}
+		if j == len(s) {
+			return j - 1
+		}
+		if s[j] == 'm' {
+			style.applySGR(s[i+2 : j])
+		}
+		return j
+	case ']': // OSC: runs to BEL or ESC \
+		for j := i + 2; j < len(s); j++ {
+			if s[j] == 0x07 {
+				return j
+			}
+			if s[j] == 0x1b && j+1 < len(s) && s[j+1] == '\\' {
+				return j + 1
+			}
+		}
+		return len(s) - 1
+	}
+	return i + 1
+}
+
+// applySGR updates the style with the parameters of one SGR sequence, e.g.
+// "1;38;5;196". An empty parameter means 0, so "" and "1;" both end in a
+// full reset. A parameter with colons, such as "38:2::255:0:0" or "4:3",
+// carries its own sub-parameters and never reads its neighbours.
+func (s *Style) applySGR(params string) {
+	codes := strings.Split(params, ";")
+	for k := 0; k < len(codes); k++ {
+		if strings.Contains(codes[k], ":") {
+			s.applySubSGR(strings.Split(codes[k], ":"))
+			continue
+		}
+		switch n := sgrNum(codes[k]); n {
+		case 38, 48:
+			// 5;N picks from the palette, 2;R;G;B gives the value
+			var c color.Color
+			switch sgrArg(codes, k+1) {
+			case 5:
+				c = color.Palette(sgrArg(codes, k+2))
+				k += 2
+			case 2:
+				c = sgrRGB(sgrArg(codes, k+2), sgrArg(codes, k+3), sgrArg(codes, k+4))
+				k += 4
+			default:
+				k = len(codes) // unknown form: the rest can't be read
+			}
+			s.setExtended(n, c)
+		default:
+			s.applyCode(n)
+		}
+	}
+}
+
+// applySubSGR applies one colon-separated parameter. Extended colours are
+// "38:5:N", "38:2:R:G:B", or "38:2:ID:R:G:B" with a colour space id; "4:N"
+// picks an underline style, where 0 turns it off.
+func (s *Style) applySubSGR(sub []string) {
+	switch n := sgrNum(sub[0]); n {
+	case 38, 48:
+		var c color.Color
+		switch sgrArg(sub, 1) {
+		case 5:
+			c = color.Palette(sgrArg(sub, 2))
+		case 2:
+			rgb := sub[2:]
+			if len(rgb) > 3 {
+				rgb = rgb[1:]
+			}
+			c = sgrRGB(sgrArg(rgb, 0), sgrArg(rgb, 1), sgrArg(rgb, 2))
+		}
+		s.setExtended(n, c)
+	case 4:
+		if sgrArg(sub, 1) == 0 {
+			s.Attrs &^= Underline
+		} else {
+			s.Attrs |= Underline
+		}
+	}
+}
+
+// applyCode applies a parameter that stands on its own
+func (s *Style) applyCode(n int) {
+	switch {
+	case n == 0:
+		*s = Style{}
+	case n == 1:
+		s.Attrs |= Bold
+	case n == 2:
+		s.Attrs |= Dim
+	case n == 3:
+		s.Attrs |= Italic
+	case n == 4:
+		s.Attrs |= Underline
+	case n == 5 || n == 6:
+		s.Attrs |= Blink
+	case n == 7:
+		s.Attrs |= Reverse
+	case n == 22:
+		s.Attrs &^= Bold | Dim
+	case n == 23:
+		s.Attrs &^= Italic
+	case n == 24:
+		s.Attrs &^= Underline
+	case n == 25:
+		s.Attrs &^= Blink
+	case n == 27:
+		s.Attrs &^= Reverse
+	case n >= 30 && n <= 37:
+		s.FG = color.Palette(n - 30)
+	case n >= 90 && n <= 97:
+		s.FG = color.Palette(n - 90 + 8)
+	case n >= 40 && n <= 47:
+		s.BG = color.Palette(n - 40)
+	case n >= 100 && n <= 107:
+		s.BG = color.Palette(n - 100 + 8)
+	case n == 39:
+		s.FG = color.Color{}
+	case n == 49:
+		s.BG = color.Color{}
+	}
+}
+
+// setExtended sets the foreground for 38 and the background for 48
+func (s *Style) setExtended(n int, c color.Color) {
+	if n == 38 {
+		s.FG = c
+	} else {
+		s.BG = c
+	}
+}
+
+// sgrNum reads one parameter; empty means 0 and anything else that isn't
+// a number is -1, which matches no code
+func sgrNum(p string) int {
+	if p == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(p)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// sgrArg reads the parameter at k, or -1 when the sequence ends before it
+func sgrArg(codes []string, k int) int {
+	if k >= len(codes) {
+		return -1
+	}
+	return sgrNum(codes[k])
+}
+
+// sgrRGB returns the colour, or no colour when a component is out of range
+func sgrRGB(r, g, b int) color.Color {
+	for _, v := range []int{r, g, b} {
+		if v < 0 || v > 255 {
+			return color.Color{}
+		}
+	}
+	return color.RGB(uint8(r), uint8(g), uint8(b))
+}
@@ -0,0 +1,76 @@
+package canvas
+
+import (
+	"testing"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+func TestApplySGR(t *testing.T) {
+	red := color.Palette(1)
+	tests := []struct {
+		name   string
+		start  Style
+		params string
+		want   Style
+	}{
+		{"empty resets", Style{FG: red, Attrs: Bold}, "", Style{}},
+		{"zero resets", Style{FG: red, Attrs: Bold}, "0", Style{}},
+		{"empty first parameter resets", Style{Attrs: Bold}, ";31", Style{FG: red}},
+		{"empty last parameter resets", Style{}, "1;", Style{}},
+		{"empty middle parameter resets", Style{}, "1;;31", Style{FG: red}},
+		{"attributes", Style{}, "1;3;4", Style{Attrs: Bold | Italic | Underline}},
+		{"attribute reset", Style{Attrs: Bold | Dim | Italic}, "22", Style{Attrs: Italic}},
+		{"16 colours", Style{}, "91;42", Style{FG: color.Palette(9), BG: color.Palette(2)}},
+		{"default colours", Style{FG: red, BG: red}, "39;49", Style{}},
+		{"256 colours", Style{}, "38;5;196", Style{FG: color.Palette(196)}},
+		{"truecolor", Style{}, "48;2;1;2;3", Style{BG: color.RGB(1, 2, 3)}},
+		{"truecolor then bold", Style{}, "38;2;1;2;3;1", Style{FG: color.RGB(1, 2, 3), Attrs: Bold}},
+		{"truecolor out of range", Style{FG: red}, "38;2;1;2;300", Style{}},
+		{"unknown extended form", Style{}, "38;9;1;1", Style{}},
+		{"colon 256 colours", Style{}, "38:5:21;1", Style{FG: color.Palette(21), Attrs: Bold}},
+		{"colon truecolor", Style{}, "38:2:1:2:3", Style{FG: color.RGB(1, 2, 3)}},
+		{"colon truecolor with colour space", Style{}, "48:2::1:2:3", Style{BG: color.RGB(1, 2, 3)}},
+		{"colon underline off", Style{Attrs: Underline | Bold}, "4:0", Style{Attrs: Bold}},
+		{"colon underline style", Style{}, "4:3", Style{Attrs: Underline}},
+		{"unknown codes ignored", Style{Attrs: Bold}, "58;x", Style{Attrs: Bold}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.start
+			s.applySGR(tt.params)
+			if s != tt.want {
+				t.Errorf("applySGR(%q) = %+v, want %+v", tt.params, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromANSI(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		plain  string
+		styles []Style // style of each cell of the first row
+	}{
+		{"empty parameter resets bold", "\x1b[1mA\x1b[;31mB", "AB\n",
+			[]Style{{Attrs: Bold}, {FG: color.Palette(1)}}},
+		{"bare reset", "\x1b[1mA\x1b[mB", "AB\n", []Style{{Attrs: Bold}, {}}},
+		{"other escapes dropped", "\x1b[2JA\x1b]0;title\x07B", "AB\n", []Style{{}, {}}},
+		{"tab stops", "A\tB", "A       B\n", nil},
+		{"sauce record ignored", "AB\r\n\x1aSAUCE00", "AB\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := FromANSI(tt.in)
+			if got := c.Plain(); got != tt.plain {
+				t.Errorf("Plain() = %q, want %q", got, tt.plain)
+			}
+			for i, want := range tt.styles {
+				if got := c.Rows[0].Cells[i].Style; got != want {
+					t.Errorf("cell %d style = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
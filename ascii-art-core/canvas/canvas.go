@@ -0,0 +1,122 @@
+// Package canvas holds rendered ASCII art as a grid of styled cells, so one
+// render can be printed to a terminal, previewed in a browser or exported.
+package canvas
+
+import (
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+// BandHeight is the number of rows every banner glyph occupies.
+const BandHeight = 8
+
+// Style describes how a cell is painted.
+type Style struct {
+	FG    color.Color
+	BG    color.Color
+	Attrs Attr
+}
+
+// Cell is a single character of the rendered art.
+type Cell struct {
+	Ch    byte
+	Style Style
+	Src   int // byte offset of the input character this cell draws, -1 for padding
+}
+
+// Row is one output line.
+type Row struct {
+	Cells []Cell
+	Band  int // row within its glyph band (0..BandHeight-1), -1 for blank lines
+}
+
+// Canvas is a rendered piece of art.
+type Canvas struct {
+	Rows []Row
+}
+
+// Pad returns n unstyled space cells that belong to no input character.
+func Pad(n int) []Cell {
+	cells := make([]Cell, n)
+	for i := range cells {
+		cells[i] = Cell{Ch: ' ', Src: -1}
+	}
+	return cells
+}
+
+// AddBand appends the rows of one rendered text line.
+func (c *Canvas) AddBand(rows [][]Cell) {
+	for i, r := range rows {
+		c.Rows = append(c.Rows, Row{Cells: r, Band: i})
+	}
+}
+
+// AddBlank appends an empty line.
+func (c *Canvas) AddBlank() {
+	c.Rows = append(c.Rows, Row{Band: -1})
+}
+
+// Clone returns a copy of the canvas whose cells can be changed freely.
+func (c *Canvas) Clone() *Canvas {
+	out := &Canvas{Rows: make([]Row, len(c.Rows))}
+	for i, r := range c.Rows {
+		out.Rows[i] = Row{Cells: append([]Cell(nil), r.Cells...), Band: r.Band}
+	}
+	return out
+}
+
+// Width returns the length of the longest row.
+func (c *Canvas) Width() int {
+	w := 0
+	for _, r := range c.Rows {
+		if len(r.Cells) > w {
+			w = len(r.Cells)
+		}
+	}
+	return w
+}
+
+// Plain returns the art without any styling, one row per line.
+func (c *Canvas) Plain() string {
+	var b strings.Builder
+	for _, r := range c.Rows {
+		for _, cell := range r.Cells {
+			b.WriteByte(cell.Ch)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// ANSI returns the art with SGR escape codes, one row per line. Every
+// styled run is opened with its own code and closed with a reset; spaces
+// without a background or underline look the same in any run, so they
+// never split one.
+func (c *Canvas) ANSI(truecolor bool) string {
+	var b strings.Builder
+	for _, r := range c.Rows {
+		cur, curStyle := "", Style{}
+		for _, cell := range r.Cells {
+			if cell.Ch == ' ' && cur != "" && !cell.Style.showsOnSpace() && !curStyle.showsOnSpace() {
+				b.WriteByte(cell.Ch)
+				continue
+			}
+			if code := cell.Style.sgr(truecolor); code != cur {
+				if cur != "" {
+					b.WriteString("\033[0m")
+				}
+				if code != "" {
+					b.WriteString("\033[" + code + "m")
+				}
+				cur, curStyle = code, cell.Style
+			}
+			b.WriteByte(cell.Ch)
+		}
+		if cur != "" {
+			b.WriteString("\033[0m")
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
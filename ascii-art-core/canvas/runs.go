@@ -0,0 +1,70 @@
+package canvas
+
+import "strings"
+
+// Run is a stretch of one row drawn with the same style.
+type Run struct {
+	Col   int // column of the first cell
+	Text  string
+	Style Style
+}
+
+// Runs splits the row into styled runs. Like ANSI, it lets spaces that look
+// the same in any style join the run around them.
+func (r Row) Runs() []Run {
+	var runs []Run
+	var b strings.Builder
+	cur := Run{Col: -1}
+	flush := func() {
+		if cur.Col >= 0 {
+			cur.Text = b.String()
+			runs = append(runs, cur)
+		}
+		b.Reset()
+	}
+	for x, cell := range r.Cells {
+		joins := cur.Col >= 0 && (cell.Style == cur.Style ||
+			cell.Ch == ' ' && !cell.Style.showsOnSpace() && !cur.Style.showsOnSpace())
+		if !joins {
+			flush()
+			cur = Run{Col: x, Style: cell.Style}
+		}
+		b.WriteByte(cell.Ch)
+	}
+	flush()
+	return runs
+}
+
+// Styled reports whether any cell of the canvas has a style.
+func (c *Canvas) Styled() bool {
+	for _, r := range c.Rows {
+		for _, cell := range r.Cells {
+			if cell.Style != (Style{}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FromText builds an unstyled canvas from already rendered plain art, such
+// as a saved .txt file. Glyph bands are guessed from runs of non-empty lines.
+func FromText(s string) *Canvas {
+	c := &Canvas{}
+	s = strings.TrimSuffix(strings.ReplaceAll(s, "\r", ""), "\n")
+	band := 0
+	for _, line := range strings.Split(s, "\n") {
+		if line == "" {
+			c.AddBlank()
+			band = 0
+			continue
+		}
+		row := Row{Band: band % BandHeight}
+		for i := 0; i < len(line); i++ {
+			row.Cells = append(row.Cells, Cell{Ch: line[i], Src: -1})
+		}
+		c.Rows = append(c.Rows, row)
+		band++
+	}
+	return c
+}
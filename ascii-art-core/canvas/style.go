@@ -0,0 +1,94 @@
+package canvas
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Attr is a set of text attributes.
+type Attr uint8
+
+const (
+	Bold Attr = 1 << iota
+	Dim
+	Italic
+	Underline
+	Blink
+	Reverse
+)
+
+// attrNames lists the attributes in SGR order with their names and codes
+var attrNames = []struct {
+	attr Attr
+	name string
+	code int
+}{
+	{Bold, "bold", 1},
+	{Dim, "dim", 2},
+	{Italic, "italic", 3},
+	{Underline, "underline", 4},
+	{Blink, "blink", 5},
+	{Reverse, "reverse", 7},
+}
+
+// ParseAttr converts an attribute name such as "bold" into an Attr.
+func ParseAttr(name string) (Attr, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, a := range attrNames {
+		if a.name == name {
+			return a.attr, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown text attribute %q (use bold, dim, italic, underline, blink or reverse)", name)
+}
+
+// Names returns the attribute names in a, e.g. ["bold", "underline"].
+func (a Attr) Names() []string {
+	var names []string
+	for _, n := range attrNames {
+		if a&n.attr != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return names
+}
+
+// SplitStyle breaks a style spec written as "fg/bg+attr+attr" into its
+// parts; every part is optional, so "red", "/yellow" and "+bold" are valid.
+// Colours are returned unparsed so each tool can apply its own colour rules.
+func SplitStyle(spec string) (fg, bg string, attrs Attr, err error) {
+	parts := strings.Split(spec, "+")
+	colors := parts[0]
+	for _, name := range parts[1:] {
+		a, err := ParseAttr(name)
+		if err != nil {
+			return "", "", 0, err
+		}
+		attrs |= a
+	}
+	fg, bg, _ = strings.Cut(colors, "/")
+	return strings.TrimSpace(fg), strings.TrimSpace(bg), attrs, nil
+}
+
+// sgr returns the SGR parameters for the style, "" for the terminal default
+func (s Style) sgr(truecolor bool) string {
+	var params []string
+	for _, n := range attrNames {
+		if s.Attrs&n.attr != 0 {
+			params = append(params, strconv.Itoa(n.code))
+		}
+	}
+	if s.FG.Valid() {
+		params = append(params, s.FG.FG(truecolor))
+	}
+	if s.BG.Valid() {
+		params = append(params, s.BG.BG(truecolor))
+	}
+	return strings.Join(params, ";")
+}
+
+// showsOnSpace reports whether a space painted with s differs from a plain one
+func (s Style) showsOnSpace() bool {
+	return s.BG.Valid() || s.Attrs&(Underline|Reverse) != 0
+}
@@ -0,0 +1,247 @@
+// Package color parses colour specifications shared by the terminal and web
+// tools and converts them to terminal escape parameters.
+package color
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Color is an sRGB colour. The zero value means "no colour" and leaves the
+// terminal default untouched.
+type Color struct {
+	R, G, B uint8
+	index   int16 // xterm palette index + 1 for named terminal colours, 0 otherwise
+	ok      bool
+}
+
+// RGB returns the colour with the given components.
+func RGB(r, g, b uint8) Color {
+	return Color{R: r, G: g, B: b, ok: true}
+}
+
+// Valid reports whether c holds a colour.
+func (c Color) Valid() bool {
+	return c.ok
+}
+
+// Hex returns the colour as "#rrggbb".
+func (c Color) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// FG returns the SGR parameters that select c as foreground colour.
+// Named terminal colours keep their palette slot; other colours use the
+// 6×6×6 cube unless truecolor is set.
+func (c Color) FG(truecolor bool) string {
+	switch {
+	case c.index > 0 && c.index <= 8:
+		return strconv.Itoa(30 + int(c.index) - 1)
+	case c.index > 0:
+		return "38;5;" + strconv.Itoa(int(c.index)-1)
+	case truecolor:
+		return fmt.Sprintf("38;2;%d;%d;%d", c.R, c.G, c.B)
+	}
+	return "38;5;" + strconv.Itoa(c.cube())
+}
+
+// BG returns the SGR parameters that select c as background colour.
+func (c Color) BG(truecolor bool) string {
+	switch {
+	case c.index > 0 && c.index <= 8:
+		return strconv.Itoa(40 + int(c.index) - 1)
+	case c.index > 0:
+		return "48;5;" + strconv.Itoa(int(c.index)-1)
+	case truecolor:
+		return fmt.Sprintf("48;2;%d;%d;%d", c.R, c.G, c.B)
+	}
+	return "48;5;" + strconv.Itoa(c.cube())
+}
+
+// cube returns the xterm-256 colour cube entry for c
+func (c Color) cube() int {
+	r6 := int(c.R) * 6 / 256
+	g6 := int(c.G) * 6 / 256
+	b6 := int(c.B) * 6 / 256
+	return 16 + (36 * r6) + (6 * g6) + b6
+}
+
+// paletteColor builds a named colour bound to an xterm palette slot
+func paletteColor(index int16, r, g, b uint8) Color {
+	return Color{R: r, G: g, B: b, index: index + 1, ok: true}
+}
+
+// Palette returns slot n (0–255) of the xterm palette, which terminals
+// select by number rather than by value. The first 16 slots use xterm's
+// default colours; anything outside the palette is no colour.
+func Palette(n int) Color {
+	switch {
+	case n < 0 || n > 255:
+		return Color{}
+	case n < 16:
+		v := basic[n]
+		return paletteColor(int16(n), v[0], v[1], v[2])
+	case n < 232:
+		c := n - 16
+		return paletteColor(int16(n), cubeLevel(c/36), cubeLevel(c/6%6), cubeLevel(c%6))
+	}
+	g := uint8(8 + (n-232)*10)
+	return paletteColor(int16(n), g, g, g)
+}
+
+// basic holds xterm's default values for the 16 basic colours
+var basic = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// cubeLevel is the value of step i (0–5) of the 6×6×6 colour cube
+func cubeLevel(i int) uint8 {
+	if i == 0 {
+		return 0
+	}
+	return uint8(55 + i*40)
+}
+
+// named holds the xterm palette slot of each terminal colour name. Terminal
+// output selects the slot, so the name looks the way the user's terminal
+// draws it; HTML, SVG and the image formats use the CSS value of the name.
+var named = map[string]int16{
+	"black":   0,
+	"red":     1,
+	"green":   2,
+	"yellow":  3,
+	"blue":    4,
+	"magenta": 5,
+	"cyan":    6,
+	"white":   7,
+	"orange":  208, // approximate orange in 256-color
+	"pink":    205,
+	"purple":  93,
+	"gray":    240,
+	"grey":    240,
+	"brown":   94,
+}
+
+// Parse converts a colour name, rgb(r, g, b), #rgb, #rrggbb or
+// hsl(h, s%, l%) into a Color. Names are the CSS named colours, and the
+// terminal colours above also keep their palette slot; #rgba and #rrggbbaa are accepted too, but the alpha is
+// dropped as cells are opaque. hsl() takes decimals, and both functions
+// also take the space-separated CSS form, e.g. hsl(210 50% 40.5%).
+func Parse(code string) (Color, error) {
+	spec := code
+	code = strings.ToLower(strings.TrimSpace(code))
+	if c, ok := cssNamed[code]; ok {
+		if slot, ok := named[code]; ok {
+			c.index = slot + 1
+		}
+		return c, nil
+	}
+
+	// #rgb, #rgba, #rrggbb and #rrggbbaa
+	if strings.HasPrefix(code, "#") {
+		if c, ok := parseHex(code[1:]); ok {
+			return c, nil
+		}
+	}
+
+	// rgb(r, g, b)
+	if parts, ok := colorFunc(code, "rgb"); ok {
+		r, err1 := strconv.Atoi(parts[0])
+		g, err2 := strconv.Atoi(parts[1])
+		b, err3 := strconv.Atoi(parts[2])
+		if err1 == nil && err2 == nil && err3 == nil && inByte(r) && inByte(g) && inByte(b) {
+			return RGB(uint8(r), uint8(g), uint8(b)), nil
+		}
+	}
+
+	// hsl(h, s%, l%)
+	if parts, ok := colorFunc(code, "hsl"); ok {
+		h, err1 := strconv.ParseFloat(strings.TrimSuffix(parts[0], "deg"), 64)
+		s, err2 := strconv.ParseFloat(strings.TrimSuffix(parts[1], "%"), 64)
+		l, err3 := strconv.ParseFloat(strings.TrimSuffix(parts[2], "%"), 64)
+		if err1 == nil && err2 == nil && err3 == nil && inPercent(s) && inPercent(l) && !math.IsInf(h, 0) && !math.IsNaN(h) {
+			return hslToRgb(h, s/100, l/100), nil
+		}
+	}
+
+	return Color{}, fmt.Errorf("invalid color %q", spec)
+}
+
+// parseHex reads the hex digits of #rgb, #rgba, #rrggbb or #rrggbbaa
+func parseHex(digits string) (Color, bool) {
+	if _, err := strconv.ParseUint(digits, 16, 32); err != nil {
+		return Color{}, false
+	}
+	switch len(digits) {
+	case 3, 4:
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	case 6, 8:
+		digits = digits[:6]
+	default:
+		return Color{}, false
+	}
+	v, _ := strconv.ParseUint(digits, 16, 32)
+	return RGB(uint8(v>>16), uint8(v>>8), uint8(v)), true
+}
+
+// colorFunc splits "name(a, b, c)" or "name(a b c)" into its three
+// arguments
+func colorFunc(code, name string) ([]string, bool) {
+	if !strings.HasPrefix(code, name+"(") || !strings.HasSuffix(code, ")") {
+		return nil, false
+	}
+	parts := strings.FieldsFunc(code[len(name)+1:len(code)-1], func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	return parts, len(parts) == 3
+}
+
+func inByte(v int) bool {
+	return v >= 0 && v <= 255
+}
+
+func inPercent(v float64) bool {
+	return v >= 0 && v <= 100
+}
+
+// hslToRgb converts HSL colour values to RGB
+func hslToRgb(h, s, l float64) Color {
+	h = math.Mod(math.Mod(h, 360)+360, 360)
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var r1, g1, b1 float64
+	switch {
+	case h < 60:
+		r1, g1, b1 = c, x, 0
+	case h < 120:
+		r1, g1, b1 = x, c, 0
+	case h < 180:
+		r1, g1, b1 = 0, c, x
+	case h < 240:
+		r1, g1, b1 = 0, x, c
+	case h < 300:
+		r1, g1, b1 = x, 0, c
+	default:
+		r1, g1, b1 = c, 0, x
+	}
+
+	return RGB(clampByte((r1+m)*255), clampByte((g1+m)*255), clampByte((b1+m)*255))
+}
+
+// clampByte rounds v to the nearest value in 0–255
+func clampByte(v float64) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	}
+	return uint8(v + 0.5)
+}
@@ -0,0 +1,35 @@
+package color
+
+import "testing"
+
+func TestParseNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		hex    string
+		fg, bg string
+	}{
+		{"red", "#ff0000", "31", "41"},
+		{"Green", "#008000", "32", "42"},
+		{" white ", "#ffffff", "37", "47"},
+		{"purple", "#800080", "38;5;93", "48;5;93"},
+		{"grey", "#808080", "38;5;240", "48;5;240"},
+		{"navy", "#000080", "38;2;0;0;128", "48;2;0;0;128"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.Hex(); got != tt.hex {
+				t.Errorf("Hex() = %s, want the CSS value %s", got, tt.hex)
+			}
+			if got := c.FG(true); got != tt.fg {
+				t.Errorf("FG(true) = %q, want %q", got, tt.fg)
+			}
+			if got := c.BG(true); got != tt.bg {
+				t.Errorf("BG(true) = %q, want %q", got, tt.bg)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package color
+
+import "math"
+
+// oklab is a colour in Björn Ottosson's OKLab space, where equal distances
+// look like equal differences, so blends don't pass through muddy greys.
+type oklab struct {
+	L, A, B float64
+}
+
+// Mix blends a and b in OKLab space; t=0 returns a and t=1 returns b.
+func Mix(a, b Color, t float64) Color {
+	switch {
+	case t <= 0:
+		return a
+	case t >= 1:
+		return b
+	}
+	la, lb := a.oklab(), b.oklab()
+	return fromOKLab(oklab{
+		L: la.L + (lb.L-la.L)*t,
+		A: la.A + (lb.A-la.A)*t,
+		B: la.B + (lb.B-la.B)*t,
+	})
+}
+
+func (c Color) oklab() oklab {
+	r, g, b := toLinear(c.R), toLinear(c.G), toLinear(c.B)
+
+	l := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*b)
+	m := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*b)
+	s := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*b)
+
+	return oklab{
+		L: 0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		A: 1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		B: 0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+	}
+}
+
+func fromOKLab(c oklab) Color {
+	l := c.L + 0.3963377774*c.A + 0.2158037573*c.B
+	m := c.L - 0.1055613458*c.A - 0.0638541728*c.B
+	s := c.L - 0.0894841775*c.A - 1.2914855480*c.B
+	l, m, s = l*l*l, m*m*m, s*s*s
+
+	return RGB(
+		fromLinear(+4.0767416621*l-3.3077115913*m+0.2309699292*s),
+		fromLinear(-1.2684380046*l+2.6097574011*m-0.3413193965*s),
+		fromLinear(-0.0041960863*l-0.7034186147*m+1.7076147010*s),
+	)
+}
+
+// toLinear undoes the sRGB transfer curve
+func toLinear(v uint8) float64 {
+	f := float64(v) / 255
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
+
+// fromLinear applies the sRGB transfer curve
+func fromLinear(f float64) uint8 {
+	if f <= 0.0031308 {
+		f *= 12.92
+	} else {
+		f = 1.055*math.Pow(f, 1/2.4) - 0.055
+	}
+	return clampByte(f * 255)
+}
+
+// RotateHue turns the colour's hue by deg degrees around the OKLab colour
+// wheel, keeping its lightness and chroma. Greys have no hue and come back
+// unchanged.
+func RotateHue(c Color, deg float64) Color {
+	lab := c.oklab()
+	sin, cos := math.Sincos(deg * math.Pi / 180)
+	return fromOKLab(oklab{
+		L: lab.L,
+		A: lab.A*cos - lab.B*sin,
+		B: lab.A*sin + lab.B*cos,
+	})
+}
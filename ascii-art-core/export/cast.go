@@ -0,0 +1,103 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+)
+
+func init() {
+	Register(&Exporter{
+		Name:        "cast",
+		Ext:         "cast",
+		ContentType: "application/x-asciicast",
+		Write:       writeCast,
+		Frames: func(c *canvas.Canvas, opts Options) int {
+			if opts.Animation == "" {
+				return 1
+			}
+			// every loop is written out, so a huge count saturates
+			// rather than wrapping around
+			frames, loops := animationFrames(c, opts.Animation), max(opts.Loops, 1)
+			if loops > math.MaxInt/frames {
+				return math.MaxInt
+			}
+			return frames * loops
+		},
+	})
+}
+
+// castHeader is the first line of an asciinema v2 recording
+type castHeader struct {
+	Version int               `json:"version"`
+	Width   int               `json:"width"`
+	Height  int               `json:"height"`
+	Env     map[string]string `json:"env"`
+}
+
+// Terminal sequences used between frames
+const (
+	castClear      = "\x1b[2J\x1b[H"
+	castHome       = "\x1b[H"
+	castHideCursor = "\x1b[?25l"
+	castShowCursor = "\x1b[?25h"
+)
+
+// writeCast records the art as an asciinema v2 file: a JSON header, then
+// [time, "o", data] events. Without an animation the whole render is printed
+// in one event; with one, each frame of each loop is an event that redraws
+// the screen after its delay, and the animation plays Loops times (once
+// when 0).
+func writeCast(w io.Writer, c *canvas.Canvas, opts Options) error {
+	if err := CheckAnimation(opts); err != nil {
+		return err
+	}
+	frames, holds, passes := []*canvas.Canvas{c}, []int{0}, 1
+	if opts.Animation != "" {
+		frames, holds = animations[opts.Animation](c)
+		passes = max(opts.Loops, 1)
+	}
+	delay := opts.Delay
+	if delay <= 0 {
+		delay = defaultDelay
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	width, height := c.Width(), len(c.Rows)
+	for _, f := range frames {
+		width = max(width, f.Width())
+	}
+	header := castHeader{Version: 2, Width: max(width, 1), Height: height + 1,
+		Env: map[string]string{"TERM": "xterm-256color"}}
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+
+	at := 0 // milliseconds, kept whole so timestamps don't drift
+	emit := func(data string) error {
+		return enc.Encode([]any{float64(at) / 1000, "o", data})
+	}
+	if err := emit(castHideCursor + castClear); err != nil {
+		return err
+	}
+	for pass := 0; pass < passes; pass++ {
+		for i, f := range frames {
+			if err := emit(castHome + castFrame(f, opts.TrueColor)); err != nil {
+				return err
+			}
+			at += delay * holds[i]
+		}
+	}
+	return emit("\r\n" + castShowCursor)
+}
+
+// castFrame renders a frame as ANSI with terminal line endings and no
+// newline after the last row, so redrawing it never scrolls the screen
+func castFrame(c *canvas.Canvas, truecolor bool) string {
+	out := strings.TrimSuffix(c.ANSI(truecolor), "\n")
+	return strings.ReplaceAll(out, "\n", "\r\n")
+}
@@ -0,0 +1,295 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+func init() {
+	Register(&Exporter{
+		Name:        "markdown",
+		Ext:         "md",
+		ContentType: "text/markdown; charset=utf-8",
+		Write:       writeMarkdown,
+	})
+	for _, p := range chatPlatforms {
+		Register(&Exporter{
+			Name:        p.name,
+			Ext:         "txt",
+			ContentType: "text/plain; charset=utf-8",
+			Write:       p.write,
+		})
+	}
+	Register(&Exporter{
+		Name:        "mirc",
+		Ext:         "txt",
+		ContentType: "text/plain; charset=utf-8",
+		Write:       writeMIRC,
+	})
+}
+
+// chatPlatform describes the limits of a chat that renders code blocks
+type chatPlatform struct {
+	name    string
+	title   string
+	limit   int // characters per message
+	columns int // characters a code block usually shows before wrapping
+}
+
+var chatPlatforms = []chatPlatform{
+	{name: "discord", title: "Discord", limit: 2000, columns: 80},
+	{name: "slack", title: "Slack", limit: 4000, columns: 100},
+}
+
+// IRC servers cut messages at 512 bytes including the command and target
+const (
+	ircColumns  = 80
+	ircLineSize = 400
+)
+
+// writeMarkdown wraps the art in a fenced code block
+func writeMarkdown(w io.Writer, c *canvas.Canvas, _ Options) error {
+	lines := chatLines(c.Rows)
+	_, err := io.WriteString(w, fenced(lines, fence(lines)))
+	return err
+}
+
+// write splits the art into code-block messages under the platform's limit.
+// Messages break only between glyph lines; a single glyph line too long for
+// one message is cut into column strips of full height.
+func (p chatPlatform) write(w io.Writer, c *canvas.Canvas, opts Options) error {
+	if width := c.Width(); width > p.columns {
+		opts.warn("the art is %d columns wide; %s code blocks usually show about %d, so lines may wrap", width, p.title, p.columns)
+	}
+	lines := chatLines(c.Rows)
+	mark := fence(lines)
+	room := p.limit - len(fenced(nil, mark))
+
+	var messages []string
+	var current []string
+	size := 0
+	cut := false
+	for _, band := range chatBands(c.Rows) {
+		pieces := fitBand(band, room)
+		cut = cut || len(pieces) > 1
+		for _, piece := range pieces {
+			n := blockSize(piece)
+			if size+n > room && len(current) > 0 {
+				messages = append(messages, fenced(current, mark))
+				current, size = nil, 0
+			}
+			current = append(current, piece...)
+			size += n
+		}
+	}
+	if len(current) > 0 {
+		messages = append(messages, fenced(current, mark))
+	}
+	if cut {
+		opts.warn("a glyph line is too wide for one %s message and was cut into strips", p.title)
+	}
+	if len(messages) > 1 {
+		opts.warn("split into %d %s messages of at most %d characters; send them in order", len(messages), p.title, p.limit)
+	}
+	_, err := io.WriteString(w, strings.Join(messages, "\n"))
+	return err
+}
+
+// chatBands groups rows into glyph lines: a band starts at each first glyph
+// row, and empty lines stay with the band before them
+func chatBands(rows []canvas.Row) [][]string {
+	var bands [][]string
+	for i, r := range rows {
+		if i == 0 || r.Band == 0 {
+			bands = append(bands, nil)
+		}
+		last := len(bands) - 1
+		bands[last] = append(bands[last], strings.TrimRight(plainRow(r), " "))
+	}
+	return bands
+}
+
+// fitBand returns the band unchanged if it fits in room characters, or cut
+// into column strips that do
+func fitBand(band []string, room int) [][]string {
+	if blockSize(band) <= room {
+		return [][]string{band}
+	}
+	width := 0
+	for _, line := range band {
+		width = max(width, len(line))
+	}
+	step := max(room/len(band)-1, 1)
+	var strips [][]string
+	for from := 0; from < width; from += step {
+		strip := make([]string, len(band))
+		for i, line := range band {
+			if from < len(line) {
+				strip[i] = strings.TrimRight(line[from:min(from+step, len(line))], " ")
+			}
+		}
+		strips = append(strips, strip)
+	}
+	return strips
+}
+
+// blockSize counts the characters the lines take inside a code block
+func blockSize(lines []string) int {
+	n := 0
+	for _, l := range lines {
+		n += len(l) + 1
+	}
+	return n
+}
+
+// fence returns a code fence longer than any run of backticks in the art
+func fence(lines []string) string {
+	longest := 0
+	for _, l := range lines {
+		run := 0
+		for i := 0; i < len(l); i++ {
+			if l[i] == '`' {
+				run++
+				longest = max(longest, run)
+			} else {
+				run = 0
+			}
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// fenced writes lines as one code block
+func fenced(lines []string, mark string) string {
+	var b strings.Builder
+	b.WriteString(mark + "\n")
+	for _, l := range lines {
+		b.WriteString(l + "\n")
+	}
+	b.WriteString(mark + "\n")
+	return b.String()
+}
+
+// chatLines returns the rows as text without trailing spaces
+func chatLines(rows []canvas.Row) []string {
+	lines := make([]string, len(rows))
+	for i, r := range rows {
+		lines[i] = strings.TrimRight(plainRow(r), " ")
+	}
+	return lines
+}
+
+// plainRow returns the characters of one row
+func plainRow(r canvas.Row) string {
+	b := make([]byte, len(r.Cells))
+	for i, cell := range r.Cells {
+		b[i] = cell.Ch
+	}
+	return string(b)
+}
+
+// mIRC control codes
+const (
+	ircColor     = "\x03"
+	ircBold      = "\x02"
+	ircItalic    = "\x1d"
+	ircUnderline = "\x1f"
+	ircReverse   = "\x16"
+	ircReset     = "\x0f"
+)
+
+// ircColors are the sixteen standard mIRC colours in code order
+var ircColors = []color.Color{
+	color.RGB(255, 255, 255), color.RGB(0, 0, 0), color.RGB(0, 0, 127), color.RGB(0, 147, 0),
+	color.RGB(255, 0, 0), color.RGB(127, 0, 0), color.RGB(156, 0, 156), color.RGB(252, 127, 0),
+	color.RGB(255, 255, 0), color.RGB(0, 252, 0), color.RGB(0, 147, 147), color.RGB(0, 255, 255),
+	color.RGB(0, 0, 252), color.RGB(255, 0, 255), color.RGB(127, 127, 127), color.RGB(210, 210, 210),
+}
+
+// writeMIRC writes one IRC message per row, with colours matched to the
+// nearest of the sixteen mIRC colours
+func writeMIRC(w io.Writer, c *canvas.Canvas, opts Options) error {
+	if width := c.Width(); width > ircColumns {
+		opts.warn("the art is %d columns wide; IRC windows usually show about %d, so lines may wrap", width, ircColumns)
+	}
+	var b strings.Builder
+	long := 0
+	for _, r := range c.Rows {
+		var line strings.Builder
+		prev := canvas.Style{}
+		for _, run := range r.Runs() {
+			if run.Style != prev {
+				line.WriteString(ircCodes(run.Style, run.Text))
+				prev = run.Style
+			}
+			line.WriteString(run.Text)
+		}
+		text := line.String()
+		if prev == (canvas.Style{}) {
+			text = strings.TrimRight(text, " ")
+		}
+		if len(text) > ircLineSize {
+			long++
+		}
+		// An empty message is dropped by IRC servers, so blank rows send a space
+		if text == "" {
+			text = " "
+		}
+		b.WriteString(text + "\n")
+	}
+	if long > 0 {
+		opts.warn("%d lines are longer than %d bytes and may be cut off by IRC servers", long, ircLineSize)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ircCodes returns the codes that switch to a style, starting from a reset
+func ircCodes(s canvas.Style, text string) string {
+	if s == (canvas.Style{}) {
+		return ircReset
+	}
+	codes := ircReset
+	if s.Attrs&canvas.Bold != 0 {
+		codes += ircBold
+	}
+	if s.Attrs&canvas.Italic != 0 {
+		codes += ircItalic
+	}
+	if s.Attrs&canvas.Underline != 0 {
+		codes += ircUnderline
+	}
+	if s.Attrs&canvas.Reverse != 0 {
+		codes += ircReverse
+	}
+	if s.FG.Valid() || s.BG.Valid() {
+		fg := 99 // the client's default colour
+		if s.FG.Valid() {
+			fg = nearestIRC(s.FG)
+		}
+		codes += fmt.Sprintf("%s%02d", ircColor, fg)
+		if s.BG.Valid() {
+			codes += fmt.Sprintf(",%02d", nearestIRC(s.BG))
+		} else if strings.HasPrefix(text, ",") {
+			// Keep a leading comma from being read as a background colour
+			codes += ircBold + ircBold
+		}
+	}
+	return codes
+}
+
+// nearestIRC returns the code of the mIRC colour closest to c
+func nearestIRC(c color.Color) int {
+	best, bestDist := 0, -1
+	for i, p := range ircColors {
+		dr, dg, db := int(c.R)-int(p.R), int(c.G)-int(p.G), int(c.B)-int(p.B)
+		if d := dr*dr + dg*dg + db*db; bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
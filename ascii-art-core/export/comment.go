@@ -0,0 +1,126 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+)
+
+func init() {
+	Register(&Exporter{
+		Name:        "comment",
+		Ext:         "txt",
+		ContentType: "text/plain; charset=utf-8",
+		Write:       writeComment,
+	})
+}
+
+// commentStyle is the comment syntax of one language: either a prefix for
+// every line, or an opening and closing pair around the block
+type commentStyle struct {
+	line        string
+	open, close string
+	inner       string // prefix of lines inside a block comment
+	unsafe      string // sequence that would end the comment early
+}
+
+var commentStyles = map[string]commentStyle{
+	"go":     {line: "//"},
+	"c":      {open: "/*", close: " */", inner: " *", unsafe: "*/"},
+	"python": {line: "#"},
+	"shell":  {line: "#"},
+	"sql":    {line: "--"},
+	"lua":    {line: "--"},
+	"html":   {open: "<!--", close: "-->", unsafe: "-->"},
+}
+
+// goConstStyle emits a Go string constant instead of a comment
+const goConstStyle = "go-const"
+
+// CheckCommentStyle reports an unknown comment style.
+func CheckCommentStyle(name string) error {
+	if _, ok := commentStyles[name]; name != "" && name != goConstStyle && !ok {
+		names := append(sortedKeys(commentStyles), goConstStyle)
+		sort.Strings(names)
+		return fmt.Errorf("unknown comment style %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// writeComment wraps the plain art in comment syntax, optionally inside a
+// box, with no trailing whitespace on any line
+func writeComment(w io.Writer, c *canvas.Canvas, opts Options) error {
+	if err := CheckCommentStyle(opts.CommentStyle); err != nil {
+		return err
+	}
+	lines := chatLines(c.Rows)
+	if opts.CommentBox {
+		lines = boxed(lines)
+	}
+	if opts.CommentStyle == goConstStyle {
+		_, err := io.WriteString(w, goConst(lines, opts.ConstName))
+		return err
+	}
+
+	name := opts.CommentStyle
+	if name == "" {
+		name = "go"
+	}
+	style := commentStyles[name]
+	var b strings.Builder
+	if style.open != "" {
+		b.WriteString(style.open + "\n")
+	}
+	for _, l := range lines {
+		if style.unsafe != "" && strings.Contains(l, style.unsafe) {
+			// Split the closing sequence so the comment can't end early
+			l = strings.ReplaceAll(l, style.unsafe, style.unsafe[:1]+" "+style.unsafe[1:])
+			opts.warn("a row contained %q, which would end the comment; it was split with a space", style.unsafe)
+		}
+		prefix := style.line + style.inner
+		b.WriteString(strings.TrimRight(prefix+" "+l, " ") + "\n")
+	}
+	if style.close != "" {
+		b.WriteString(style.close + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// boxed draws a border around the lines
+func boxed(lines []string) []string {
+	width := 0
+	for _, l := range lines {
+		width = max(width, len(l))
+	}
+	edge := "+" + strings.Repeat("-", width+2) + "+"
+	out := []string{edge}
+	for _, l := range lines {
+		out = append(out, "| "+l+strings.Repeat(" ", width-len(l))+" |")
+	}
+	return append(out, edge)
+}
+
+// goConst writes the lines as a Go string constant, one quoted line per row
+func goConst(lines []string, name string) string {
+	if name == "" {
+		name = "Banner"
+	}
+	if len(lines) == 0 {
+		return fmt.Sprintf("const %s = \"\"\n", name)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "const %s = \"\" +\n", name)
+	for i, l := range lines {
+		sep := " +"
+		if i == len(lines)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(&b, "\t%s%s\n", strconv.Quote(l+"\n"), sep)
+	}
+	return b.String()
+}
@@ -0,0 +1,160 @@
+// Package export writes a rendered canvas in the file formats shared by the
+// terminal tool (--format) and the web server (/export).
+package export
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+// Options tunes an export; each exporter ignores what it doesn't use.
+type Options struct {
+	Meta Meta             // render parameters, recorded by JSON
+	Warn func(msg string) // receives notes about the output, such as art too wide for a chat; may be nil
+
+	TrueColor bool // ANSI: use 24-bit colour codes instead of the 256-colour palette
+	SVGPixels bool // SVG: draw a filled rect per cell instead of text, for a pixel-art look
+
+	// HTML
+	HTMLTheme    string // light, dark or auto (follows the reader's preference); "" means auto
+	HTMLFragment bool   // write only the <style> and <pre>, for embedding in another page
+	InlineCSS    bool   // style attributes on every span instead of generated classes
+
+	// Source comments
+	CommentStyle string // go, c, python, shell, sql, html, lua or go-const; "" means go
+	CommentBox   bool   // draw a border around the art inside the comment
+	ConstName    string // go-const: name of the constant; "" means Banner
+
+	// PDF
+	Paper  string // a4 or letter; "" means a4
+	Poster bool   // print the art page-high and tile it across pages
+
+	// Raster formats
+	CellW, CellH int         // pixel size of one character cell; 0 means 12x16
+	Background   color.Color // page colour (also used by HTML); white when unset
+	Transparent  bool        // leave the page transparent, painting only cell backgrounds
+
+	// GIF animation
+	Animation  string // marquee, typewriter, cycle or blink; "" means marquee
+	Delay      int    // milliseconds per frame; 0 means 100
+	Loops      int    // times to play the animation; 0 loops forever
+	GIFPalette string // auto, plan9 or websafe; "" means auto
+}
+
+// Exporter writes a canvas in one file format.
+type Exporter struct {
+	Name        string // format name used by --format and the web form
+	Ext         string // file extension without the dot
+	ContentType string
+	Write       func(w io.Writer, c *canvas.Canvas, opts Options) error
+
+	// Frames counts the copies of the art a raster, animated or paged
+	// format draws, whose cost grows with them; nil for the others.
+	Frames func(c *canvas.Canvas, opts Options) int
+	Raster bool // draws an image, whose sides are bounded
+}
+
+// Limits bounds the art an exporter with Frames may be asked to draw.
+type Limits struct {
+	Cells      int // columns × rows of the art
+	FrameCells int // cells summed over every frame or page drawn
+}
+
+// maxImageSide is the longest side of an image; GIF can't store more
+const maxImageSide = 65535
+
+// CheckSize reports art too big to export within the limits, before any of
+// it is drawn. Formats without Frames are never refused.
+func (e *Exporter) CheckSize(c *canvas.Canvas, opts Options, lim Limits) error {
+	if e.Frames == nil {
+		return nil
+	}
+	cols, rows := c.Width(), len(c.Rows)
+	if cells := cols * rows; cells > lim.Cells {
+		return fmt.Errorf("the art is %d cells; %s exports are limited to %d", cells, e.Name, lim.Cells)
+	}
+	// compare by division so a huge frame count can't overflow
+	frames := e.Frames(c, opts)
+	if frames <= 0 {
+		return fmt.Errorf("%s exports can't draw %d frames", e.Name, frames)
+	}
+	if cells := cols * rows; cells > 0 && frames > lim.FrameCells/cells {
+		return fmt.Errorf("the art is %d cells in each of %d frames; %s exports are limited to %d in all",
+			cells, frames, e.Name, lim.FrameCells)
+	}
+	if e.Raster {
+		cw, ch := opts.cellSize()
+		if cols*cw > maxImageSide {
+			return fmt.Errorf("the art is %d columns wide; %s images fit at most %d", cols, e.Name, maxImageSide/cw)
+		}
+		if rows*ch > maxImageSide {
+			return fmt.Errorf("the art is %d rows tall; %s images fit at most %d", rows, e.Name, maxImageSide/ch)
+		}
+	}
+	return nil
+}
+
+// oneFrame is the Frames of formats that draw the art once
+func oneFrame(*canvas.Canvas, Options) int {
+	return 1
+}
+
+var registry = map[string]*Exporter{}
+
+// warn passes a note to opts.Warn if one is set
+func (opts Options) warn(format string, args ...any) {
+	if opts.Warn != nil {
+		opts.Warn(fmt.Sprintf(format, args...))
+	}
+}
+
+// Register makes an exporter available by name and extension.
+func Register(e *Exporter) {
+	if _, dup := registry[e.Name]; dup {
+		panic("export: duplicate format " + e.Name)
+	}
+	registry[e.Name] = e
+}
+
+// Lookup returns the exporter for a format name.
+func Lookup(name string) (*Exporter, error) {
+	e, ok := registry[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (available: %s)", name, strings.Join(Formats(), ", "))
+	}
+	return e, nil
+}
+
+// ForPath returns the exporter whose extension matches the file name. When
+// several formats share an extension, the one named after it wins.
+func ForPath(path string) (*Exporter, bool) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if ext == "" {
+		return nil, false
+	}
+	if e, ok := registry[ext]; ok && e.Ext == ext {
+		return e, true
+	}
+	for _, name := range Formats() {
+		if e := registry[name]; e.Ext == ext {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// Formats lists the registered format names in alphabetical order.
+func Formats() []string {
+	names := make([]string, 0, len(registry))
+	for n := range registry {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,46 @@
+package export
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+)
+
+func TestCheckSize(t *testing.T) {
+	art := canvas.FromText(strings.Repeat("#", 10) + "\n" + strings.Repeat("#", 10))
+	lim := Limits{Cells: 100, FrameCells: 1000}
+	tests := []struct {
+		name   string
+		format string
+		opts   Options
+		ok     bool
+	}{
+		{"still cast", "cast", Options{}, true},
+		{"cast within the frame limit", "cast", Options{Animation: "blink", Loops: 25}, true},
+		{"cast over the frame limit", "cast", Options{Animation: "blink", Loops: 26}, false},
+		{"cast loops that would overflow", "cast", Options{Animation: "blink", Loops: 1 << 62}, false},
+		{"cast with the most loops", "cast", Options{Animation: "blink", Loops: math.MaxInt}, false},
+		{"gif", "gif", Options{Animation: "blink"}, true},
+		{"text is never refused", "txt", Options{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Lookup(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := e.CheckSize(art, tt.opts, lim); (err == nil) != tt.ok {
+				t.Errorf("CheckSize = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCheckSizeRefusesNoFrames(t *testing.T) {
+	e := &Exporter{Name: "broken", Frames: func(*canvas.Canvas, Options) int { return -1 }}
+	if err := e.CheckSize(canvas.FromText("#"), Options{}, Limits{Cells: 10, FrameCells: 10}); err == nil {
+		t.Error("CheckSize accepted a negative frame count")
+	}
+}
@@ -0,0 +1,345 @@
+package export
+
+import (
+	"fmt"
+	"image"
+	icolor "image/color"
+	"image/color/palette"
+	"image/gif"
+	"io"
+	"sort"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+func init() {
+	Register(&Exporter{
+		Name:        "gif",
+		Ext:         "gif",
+		ContentType: "image/gif",
+		Write:       writeGIF,
+		Frames: func(c *canvas.Canvas, opts Options) int {
+			return animationFrames(c, opts.Animation)
+		},
+		Raster: true,
+	})
+}
+
+// Animation defaults
+const (
+	defaultAnimation = "marquee"
+	defaultDelay     = 100 // milliseconds per frame
+	marqueeGap       = 4   // blank columns between the end of the art and its next pass
+	marqueeFrames    = 48  // rough upper bound on frames per marquee pass
+	cycleFrames      = 12
+	typewriterHold   = 15 // the finished text stays this many frame delays
+)
+
+// animations builds the frames of each effect from one rendered canvas, with
+// a delay multiplier per frame.
+var animations = map[string]func(c *canvas.Canvas) ([]*canvas.Canvas, []int){
+	"marquee":    marquee,
+	"typewriter": typewriter,
+	"cycle":      cycle,
+	"blink":      blink,
+}
+
+// gifPalettes are the fixed palettes; "auto" builds one per frame from the
+// colours it actually uses.
+var gifPalettes = map[string]icolor.Palette{
+	"plan9":   palette.Plan9,
+	"websafe": palette.WebSafe,
+}
+
+// Animations lists the animations of the GIF and cast exporters.
+func Animations() []string {
+	return sortedKeys(animations)
+}
+
+// GIFPalettes lists the fixed GIF palettes; "auto" builds one from the art.
+func GIFPalettes() []string {
+	return append([]string{"auto"}, sortedKeys(gifPalettes)...)
+}
+
+// CheckAnimation reports an unknown animation or GIF palette name.
+func CheckAnimation(opts Options) error {
+	if _, ok := animations[opts.Animation]; opts.Animation != "" && !ok {
+		return fmt.Errorf("unknown animation %q (available: %s)", opts.Animation, strings.Join(sortedKeys(animations), ", "))
+	}
+	if _, ok := gifPalettes[opts.GIFPalette]; opts.GIFPalette != "" && opts.GIFPalette != "auto" && !ok {
+		return fmt.Errorf("unknown GIF palette %q (available: auto, %s)", opts.GIFPalette, strings.Join(sortedKeys(gifPalettes), ", "))
+	}
+	return nil
+}
+
+// writeGIF renders the frames of an animation and encodes them as a GIF
+func writeGIF(w io.Writer, c *canvas.Canvas, opts Options) error {
+	if err := CheckAnimation(opts); err != nil {
+		return err
+	}
+	name := opts.Animation
+	if name == "" {
+		name = defaultAnimation
+	}
+	delay := opts.Delay
+	if delay <= 0 {
+		delay = defaultDelay
+	}
+
+	frames, holds := animations[name](c)
+	anim := &gif.GIF{LoopCount: gifLoopCount(opts.Loops)}
+	for i, f := range frames {
+		anim.Image = append(anim.Image, toPaletted(rasterize(f, opts), opts))
+		// GIF delays are in hundredths of a second
+		anim.Delay = append(anim.Delay, max(delay*holds[i]/10, 1))
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// gifLoopCount maps "play n times" (0 for forever) onto the GIF field, which
+// counts repeats and uses -1 for a single pass
+func gifLoopCount(loops int) int {
+	switch {
+	case loops <= 0:
+		return 0
+	case loops == 1:
+		return -1
+	default:
+		return loops - 1
+	}
+}
+
+// toPaletted converts a frame to the requested palette
+func toPaletted(img *image.RGBA, opts Options) *image.Paletted {
+	b := img.Bounds()
+	if pal, ok := gifPalettes[opts.GIFPalette]; ok {
+		if opts.Transparent {
+			// A full palette gives up its second entry, a near-black blue,
+			// to make room for the transparent colour
+			rest := pal
+			if len(rest) == 256 {
+				rest = append(icolor.Palette{rest[0]}, rest[2:]...)
+			}
+			pal = append(icolor.Palette{icolor.RGBA{}}, rest...)
+		}
+		out := image.NewPaletted(b, pal)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				out.SetColorIndex(x, y, uint8(pal.Index(img.RGBAAt(x, y))))
+			}
+		}
+		return out
+	}
+
+	// Auto: an exact palette of the frame's colours, falling back to Plan 9
+	// when there are too many
+	index := map[icolor.RGBA]uint8{}
+	var pal icolor.Palette
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			px := img.RGBAAt(x, y)
+			if _, seen := index[px]; seen {
+				continue
+			}
+			if len(pal) == 256 {
+				return toPaletted(img, Options{GIFPalette: "plan9", Transparent: opts.Transparent})
+			}
+			index[px] = uint8(len(pal))
+			pal = append(pal, px)
+		}
+	}
+	out := image.NewPaletted(b, pal)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.SetColorIndex(x, y, index[img.RGBAAt(x, y)])
+		}
+	}
+	return out
+}
+
+// animationFrames counts the frames an animation draws without drawing
+// them; "" is the default animation, and unknown names count as one frame
+func animationFrames(c *canvas.Canvas, name string) int {
+	switch name {
+	case "", "marquee":
+		period, step := marqueeStep(c)
+		return (period + step - 1) / step
+	case "typewriter":
+		steps, _ := typewriterSteps(c)
+		return max(len(steps), 1)
+	case "cycle":
+		return cycleFrames
+	case "blink":
+		return 2
+	}
+	return 1
+}
+
+// marqueeStep returns the columns of one marquee pass and the shift
+// between frames
+func marqueeStep(c *canvas.Canvas) (period, step int) {
+	period = c.Width() + marqueeGap
+	return period, max(period/marqueeFrames, 1)
+}
+
+// marquee scrolls the art to the left, wrapping around after a short gap
+func marquee(c *canvas.Canvas) ([]*canvas.Canvas, []int) {
+	period, step := marqueeStep(c)
+	var frames []*canvas.Canvas
+	var holds []int
+	for shift := 0; shift < period; shift += step {
+		f := c.Clone()
+		for i, r := range c.Rows {
+			if r.Band < 0 {
+				continue
+			}
+			cells := canvas.Pad(c.Width())
+			for x := range cells {
+				if src := (x + shift) % period; src < len(r.Cells) {
+					cells[x] = r.Cells[src]
+				}
+			}
+			f.Rows[i].Cells = cells
+		}
+		frames = append(frames, f)
+		holds = append(holds, 1)
+	}
+	return frames, holds
+}
+
+// typewriter reveals the input one character at a time. Art without source
+// positions, such as plain text exports, is revealed column by column.
+func typewriter(c *canvas.Canvas) ([]*canvas.Canvas, []int) {
+	steps, key := typewriterSteps(c)
+	var frames []*canvas.Canvas
+	var holds []int
+	for _, upto := range steps {
+		f := c.Clone()
+		for _, r := range f.Rows {
+			for x, cell := range r.Cells {
+				if key(cell, x) > upto {
+					r.Cells[x] = canvas.Cell{Ch: ' ', Src: cell.Src}
+				}
+			}
+		}
+		frames = append(frames, f)
+		holds = append(holds, 1)
+	}
+	if len(frames) == 0 {
+		return []*canvas.Canvas{c}, []int{1}
+	}
+	holds[len(holds)-1] = typewriterHold
+	return frames, holds
+}
+
+// typewriterSteps returns, in order, the keys the typewriter reveals one
+// frame at a time, and the key of a cell at column x
+func typewriterSteps(c *canvas.Canvas) ([]int, func(cell canvas.Cell, x int) int) {
+	key := func(cell canvas.Cell, x int) int { return cell.Src }
+	if !hasSource(c) {
+		key = func(_ canvas.Cell, x int) int { return x }
+	}
+	seen := map[int]bool{}
+	for _, r := range c.Rows {
+		for x, cell := range r.Cells {
+			if k := key(cell, x); k >= 0 {
+				seen[k] = true
+			}
+		}
+	}
+	steps := make([]int, 0, len(seen))
+	for k := range seen {
+		steps = append(steps, k)
+	}
+	sort.Ints(steps)
+	return steps, key
+}
+
+// cycle turns every colour around the colour wheel. Uncoloured art first
+// gets a rainbow across its columns so there is something to cycle.
+func cycle(c *canvas.Canvas) ([]*canvas.Canvas, []int) {
+	base := c.Clone()
+	if !hasForeground(base) {
+		width := max(base.Width(), 1)
+		for _, r := range base.Rows {
+			for x := range r.Cells {
+				r.Cells[x].Style.FG = color.RotateHue(color.RGB(255, 0, 0), 360*float64(x)/float64(width))
+			}
+		}
+	}
+	var frames []*canvas.Canvas
+	var holds []int
+	for i := 0; i < cycleFrames; i++ {
+		f := base.Clone()
+		turn := 360 * float64(i) / cycleFrames
+		for _, r := range f.Rows {
+			for x, cell := range r.Cells {
+				if cell.Style.FG.Valid() {
+					r.Cells[x].Style.FG = color.RotateHue(cell.Style.FG, turn)
+				}
+				if cell.Style.BG.Valid() {
+					r.Cells[x].Style.BG = color.RotateHue(cell.Style.BG, turn)
+				}
+			}
+		}
+		frames = append(frames, f)
+		holds = append(holds, 1)
+	}
+	return frames, holds
+}
+
+// blink flashes the cells styled with the blink attribute, or the whole art
+// when none are, keeping their backgrounds in place
+func blink(c *canvas.Canvas) ([]*canvas.Canvas, []int) {
+	only := false
+	for _, r := range c.Rows {
+		for _, cell := range r.Cells {
+			only = only || cell.Style.Attrs&canvas.Blink != 0
+		}
+	}
+	off := c.Clone()
+	for _, r := range off.Rows {
+		for x, cell := range r.Cells {
+			if !only || cell.Style.Attrs&canvas.Blink != 0 {
+				r.Cells[x] = canvas.Cell{Ch: ' ', Style: canvas.Style{BG: cell.Style.BG}, Src: cell.Src}
+			}
+		}
+	}
+	return []*canvas.Canvas{c, off}, []int{1, 1}
+}
+
+// hasSource reports whether any cell knows its input character
+func hasSource(c *canvas.Canvas) bool {
+	for _, r := range c.Rows {
+		for _, cell := range r.Cells {
+			if cell.Src >= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasForeground reports whether any cell has a text colour
+func hasForeground(c *canvas.Canvas) bool {
+	for _, r := range c.Rows {
+		for _, cell := range r.Cells {
+			if cell.Style.FG.Valid() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sortedKeys lists the names of a lookup table in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,251 @@
+package export
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+func init() {
+	Register(&Exporter{
+		Name:        "html",
+		Ext:         "html",
+		ContentType: "text/html; charset=utf-8",
+		Write:       writeHTML,
+	})
+}
+
+// htmlTheme holds the default text and page colours of an HTML export
+type htmlTheme struct {
+	fg, bg string
+}
+
+var htmlThemes = map[string]htmlTheme{
+	"light": {fg: "#1e1e1e", bg: "#ffffff"},
+	"dark":  {fg: "#e6e6e6", bg: "#1e1e1e"},
+}
+
+// htmlAttrs maps attributes to their class name and CSS declarations
+var htmlAttrs = []struct {
+	attr  canvas.Attr
+	class string
+	css   string
+}{
+	{canvas.Bold, "bold", "font-weight:bold"},
+	{canvas.Dim, "dim", "opacity:0.6"},
+	{canvas.Italic, "italic", "font-style:italic"},
+	{canvas.Underline, "underline", "text-decoration:underline"},
+	{canvas.Blink, "blink", "animation:ascii-blink 1s steps(1) infinite"},
+}
+
+// CheckHTMLTheme reports an unknown HTML theme name.
+func CheckHTMLTheme(name string) error {
+	if _, ok := htmlThemes[name]; name != "" && name != "auto" && !ok {
+		return fmt.Errorf("unknown HTML theme %q (available: auto, dark, light)", name)
+	}
+	return nil
+}
+
+// HTMLThemes lists the page themes of the HTML exporter; "auto" follows the
+// viewer's colour scheme.
+func HTMLThemes() []string {
+	return append([]string{"auto"}, sortedKeys(htmlThemes)...)
+}
+
+// writeHTML writes the art as a <pre class="ascii-art">, either as a complete
+// document or, with opts.HTMLFragment, as a snippet to paste into a page.
+// Colour runs get generated CSS classes collected in one <style> block, or
+// style attributes with opts.InlineCSS.
+func writeHTML(w io.Writer, c *canvas.Canvas, opts Options) error {
+	if err := CheckHTMLTheme(opts.HTMLTheme); err != nil {
+		return err
+	}
+	var body strings.Builder
+	used := map[string]bool{}
+	for _, r := range c.Rows {
+		for _, run := range r.Runs() {
+			text := html.EscapeString(run.Text)
+			switch {
+			case run.Style == (canvas.Style{}):
+				body.WriteString(text)
+			case opts.InlineCSS:
+				fmt.Fprintf(&body, `<span style="%s">%s</span>`, inlineCSS(run.Style, pageTheme(opts)), text)
+			default:
+				classes := htmlClasses(run.Style)
+				for _, cl := range classes {
+					used[cl] = true
+				}
+				fmt.Fprintf(&body, `<span class="%s">%s</span>`, strings.Join(classes, " "), text)
+			}
+		}
+		body.WriteByte('\n')
+	}
+
+	var b strings.Builder
+	if !opts.HTMLFragment {
+		b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n" +
+			"<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n<title>ASCII Art</title>\n")
+	}
+	if opts.InlineCSS {
+		t := pageTheme(opts)
+		if !opts.HTMLFragment {
+			b.WriteString("</head>\n")
+			fmt.Fprintf(&b, "<body style=\"margin:0;background-color:%s\">\n", t.bg)
+		}
+		fmt.Fprintf(&b, `<pre class="ascii-art" style="%s">`, fmt.Sprintf(
+			"margin:0;padding:1em;font-family:monospace;line-height:1.15;color:%s;background-color:%s", t.fg, t.bg))
+	} else {
+		b.WriteString("<style>\n" + htmlStyleSheet(opts, used) + "</style>\n")
+		if !opts.HTMLFragment {
+			b.WriteString("</head>\n<body>\n")
+		}
+		b.WriteString(`<pre class="ascii-art">`)
+	}
+	b.WriteString(body.String())
+	b.WriteString("</pre>\n")
+	if !opts.HTMLFragment {
+		b.WriteString("</body>\n</html>\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// pageTheme resolves the default colours for inline styles, which cannot
+// follow the reader's light or dark preference
+func pageTheme(opts Options) htmlTheme {
+	name := opts.HTMLTheme
+	if name == "" || name == "auto" {
+		name = "light"
+		if opts.Background.Valid() && isDark(opts.Background) {
+			name = "dark"
+		}
+	}
+	t := htmlThemes[name]
+	if opts.Background.Valid() {
+		t.bg = opts.Background.Hex()
+	}
+	return t
+}
+
+// htmlStyleSheet writes the theme variables and one rule per class in use.
+// The auto theme follows prefers-color-scheme unless a background is chosen,
+// in which case the text colour that reads best on it is used.
+func htmlStyleSheet(opts Options, used map[string]bool) string {
+	var b strings.Builder
+	scope := ":root"
+	if opts.HTMLFragment {
+		scope = ".ascii-art"
+	}
+	vars := func(t htmlTheme) string {
+		if opts.Background.Valid() {
+			t.bg = opts.Background.Hex()
+		}
+		return fmt.Sprintf("%s { --ascii-fg: %s; --ascii-bg: %s; }\n", scope, t.fg, t.bg)
+	}
+	if name := opts.HTMLTheme; (name == "" || name == "auto") && !opts.Background.Valid() {
+		b.WriteString(vars(htmlThemes["light"]))
+		b.WriteString("@media (prefers-color-scheme: dark) {\n  " + vars(htmlThemes["dark"]) + "}\n")
+	} else {
+		b.WriteString(vars(pageTheme(opts)))
+	}
+	if !opts.HTMLFragment {
+		b.WriteString("body { margin: 0; background-color: var(--ascii-bg); }\n")
+	}
+	b.WriteString(".ascii-art { margin: 0; padding: 1em; font-family: monospace; line-height: 1.15; " +
+		"color: var(--ascii-fg); background-color: var(--ascii-bg); }\n")
+
+	// Reverse first, so explicit colour classes listed after it win
+	if used["rev"] {
+		b.WriteString(".ascii-art .rev { color: var(--ascii-bg); background-color: var(--ascii-fg); }\n")
+	}
+	var colours []string
+	for cl := range used {
+		if strings.HasPrefix(cl, "fg-") || strings.HasPrefix(cl, "bg-") {
+			colours = append(colours, cl)
+		}
+	}
+	sort.Strings(colours)
+	for _, cl := range colours {
+		prop := "color"
+		if strings.HasPrefix(cl, "bg-") {
+			prop = "background-color"
+		}
+		fmt.Fprintf(&b, ".ascii-art .%s { %s: #%s; }\n", cl, prop, cl[3:])
+	}
+	for _, a := range htmlAttrs {
+		if used[a.class] {
+			fmt.Fprintf(&b, ".ascii-art .%s { %s; }\n", a.class, a.css)
+		}
+	}
+	if used["blink"] {
+		b.WriteString("@keyframes ascii-blink { 50% { opacity: 0; } }\n")
+	}
+	return b.String()
+}
+
+// htmlClasses names the classes of a style; reverse video swaps the colour
+// classes and relies on the theme for whichever colour is unset
+func htmlClasses(s canvas.Style) []string {
+	var classes []string
+	fg, bg := "fg-", "bg-"
+	if s.Attrs&canvas.Reverse != 0 {
+		classes = append(classes, "rev")
+		fg, bg = bg, fg
+	}
+	if s.FG.Valid() {
+		classes = append(classes, fg+s.FG.Hex()[1:])
+	}
+	if s.BG.Valid() {
+		classes = append(classes, bg+s.BG.Hex()[1:])
+	}
+	for _, a := range htmlAttrs {
+		if s.Attrs&a.attr != 0 {
+			classes = append(classes, a.class)
+		}
+	}
+	return classes
+}
+
+// inlineCSS returns the declarations of a style for a style attribute
+func inlineCSS(s canvas.Style, t htmlTheme) string {
+	var css []string
+	fg, bg := "", ""
+	if s.FG.Valid() {
+		fg = s.FG.Hex()
+	}
+	if s.BG.Valid() {
+		bg = s.BG.Hex()
+	}
+	if s.Attrs&canvas.Reverse != 0 {
+		if fg == "" {
+			fg = t.fg
+		}
+		if bg == "" {
+			bg = t.bg
+		}
+		fg, bg = bg, fg
+	}
+	if fg != "" {
+		css = append(css, "color:"+fg)
+	}
+	if bg != "" {
+		css = append(css, "background-color:"+bg)
+	}
+	for _, a := range htmlAttrs {
+		// Keyframes can't be inlined, so blinking text stays still
+		if s.Attrs&a.attr != 0 && a.attr != canvas.Blink {
+			css = append(css, a.css)
+		}
+	}
+	return strings.Join(css, ";")
+}
+
+// isDark reports whether light text reads better than dark text on c
+func isDark(c color.Color) bool {
+	return 0.299*float64(c.R)+0.587*float64(c.G)+0.114*float64(c.B) < 128
+}
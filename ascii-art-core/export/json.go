@@ -0,0 +1,127 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/markup"
+)
+
+func init() {
+	Register(&Exporter{
+		Name:        "json",
+		Ext:         "json",
+		ContentType: "application/json",
+		Write:       writeJSON,
+	})
+}
+
+// JSONVersion is bumped whenever a field of the JSON export changes meaning
+// or disappears; new fields may be added without bumping it.
+const JSONVersion = 1
+
+// Meta describes the render a canvas came from. Exporters that record it
+// (JSON) leave out the fields that are unset.
+type Meta struct {
+	Text   string // input text, before markup is removed
+	Markup bool   // Text holds inline markup
+	Banner string // banner name
+	Align  string
+	Width  int // width in columns the art was aligned to
+}
+
+// jsonArt is the top level of the JSON export
+type jsonArt struct {
+	Version     int       `json:"version"`
+	Text        string    `json:"text,omitempty"`
+	Markup      string    `json:"markup,omitempty"`
+	Banner      string    `json:"banner,omitempty"`
+	Align       string    `json:"align,omitempty"`
+	Width       int       `json:"width,omitempty"`
+	GlyphHeight int       `json:"glyphHeight"`
+	Columns     int       `json:"columns"`
+	Rows        []jsonRow `json:"rows"`
+}
+
+// jsonRow is one line of the grid. Band is the row's index inside its glyph
+// line (0 to glyphHeight-1), or -1 for an empty input line.
+type jsonRow struct {
+	Text string    `json:"text"`
+	Band int       `json:"band"`
+	Runs []jsonRun `json:"runs"`
+}
+
+// jsonRun is a styled stretch of a row. Sources lists the byte offsets of
+// the input characters whose glyphs it covers.
+type jsonRun struct {
+	Col     int      `json:"col"`
+	Length  int      `json:"length"`
+	FG      string   `json:"fg,omitempty"`
+	BG      string   `json:"bg,omitempty"`
+	Attrs   []string `json:"attrs,omitempty"`
+	Sources []int    `json:"sources"`
+}
+
+// writeJSON writes the grid, its styled runs and the render parameters.
+// Sources are offsets into the recorded text, so with markup the text is
+// recorded without it, and the original goes in the markup field.
+func writeJSON(w io.Writer, c *canvas.Canvas, opts Options) error {
+	art := jsonArt{
+		Version:     JSONVersion,
+		Text:        opts.Meta.Text,
+		Banner:      opts.Meta.Banner,
+		Align:       opts.Meta.Align,
+		Width:       opts.Meta.Width,
+		GlyphHeight: canvas.BandHeight,
+		Columns:     c.Width(),
+		Rows:        make([]jsonRow, 0, len(c.Rows)),
+	}
+	if opts.Meta.Markup {
+		if res, err := markup.Parse(opts.Meta.Text); err == nil {
+			art.Text, art.Markup = res.Text, opts.Meta.Text
+		}
+	}
+	for _, r := range c.Rows {
+		row := jsonRow{Band: r.Band, Runs: []jsonRun{}}
+		for _, run := range r.Runs() {
+			row.Text += run.Text
+			if run.Style == (canvas.Style{}) {
+				continue
+			}
+			jr := jsonRun{
+				Col:     run.Col,
+				Length:  len(run.Text),
+				Attrs:   run.Style.Attrs.Names(),
+				Sources: runSources(r.Cells[run.Col : run.Col+len(run.Text)]),
+			}
+			if run.Style.FG.Valid() {
+				jr.FG = run.Style.FG.Hex()
+			}
+			if run.Style.BG.Valid() {
+				jr.BG = run.Style.BG.Hex()
+			}
+			row.Runs = append(row.Runs, jr)
+		}
+		art.Rows = append(art.Rows, row)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(art)
+}
+
+// runSources returns the distinct input offsets behind a run of cells
+func runSources(cells []canvas.Cell) []int {
+	seen := map[int]bool{}
+	srcs := []int{}
+	for _, cell := range cells {
+		if cell.Src >= 0 && !seen[cell.Src] {
+			seen[cell.Src] = true
+			srcs = append(srcs, cell.Src)
+		}
+	}
+	sort.Ints(srcs)
+	return srcs
+}
@@ -0,0 +1,260 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+func init() {
+	Register(&Exporter{
+		Name:        "pdf",
+		Ext:         "pdf",
+		ContentType: "application/pdf",
+		Write:       writePDF,
+		Frames: func(c *canvas.Canvas, opts Options) int {
+			return layoutPDF(c, opts).pages
+		},
+	})
+}
+
+// Page sizes in points, portrait
+var pdfPapers = map[string][2]float64{
+	"a4":     {595.28, 841.89},
+	"letter": {612, 792},
+}
+
+// PDF layout, in points unless noted
+const (
+	pdfMargin     = 36
+	pdfOverlap    = 36   // width printed on both neighbouring poster pages
+	pdfLeading    = 1.1  // line height, in em
+	pdfDescent    = 0.25 // baseline offset from the bottom of a line, in em
+	pdfMaxFitSize = 24   // largest font size when fitting to one page
+)
+
+// CheckPaper reports an unknown paper size.
+func CheckPaper(name string) error {
+	if _, ok := pdfPapers[name]; name != "" && !ok {
+		return fmt.Errorf("unknown paper size %q (available: a4, letter)", name)
+	}
+	return nil
+}
+
+// Papers lists the paper sizes of the PDF exporter.
+func Papers() []string {
+	return sortedKeys(pdfPapers)
+}
+
+// pdfLayout places the art on one or more pages
+type pdfLayout struct {
+	pageW, pageH float64
+	size         float64 // font size
+	pages        int
+	step         float64 // art width advanced from one poster page to the next
+}
+
+// pdfCharWidth is the advance of the embedded font, in em
+var pdfCharWidth = pdfRegular.charWidth()
+
+// writePDF writes a PDF with Go Mono embedded, its bold face too when the
+// art uses it. The art is scaled to fit the paper, turned landscape when it
+// is wide, or with opts.Poster printed tall and tiled across as many pages
+// as its width needs.
+func writePDF(w io.Writer, c *canvas.Canvas, opts Options) error {
+	if err := CheckPaper(opts.Paper); err != nil {
+		return err
+	}
+	l := layoutPDF(c, opts)
+
+	// Objects: catalog, page tree, three per font, then each page and
+	// its content stream
+	fonts := []*pdfFont{pdfRegular}
+	if usesAttr(c, canvas.Bold) {
+		fonts = append(fonts, pdfBold)
+	}
+	firstPage := 3 + 3*len(fonts)
+
+	var doc pdfWriter
+	doc.object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, l.pages)
+	for i := range kids {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	doc.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), l.pages))
+	resources := make([]string, len(fonts))
+	for i, f := range fonts {
+		for _, obj := range f.objects(3 + 3*i) {
+			doc.object(obj)
+		}
+		resources[i] = fmt.Sprintf("/F%d %d 0 R", i+1, 3+3*i)
+	}
+	for i := 0; i < l.pages; i++ {
+		doc.object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] "+
+			"/Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			pdfNum(l.pageW), pdfNum(l.pageH), strings.Join(resources, " "), firstPage+2*i+1))
+		content := pdfPage(c, opts, l, i)
+		doc.object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+	_, err := w.Write(doc.finish())
+	return err
+}
+
+// layoutPDF picks the orientation, font size and page count
+func layoutPDF(c *canvas.Canvas, opts Options) pdfLayout {
+	paper := pdfPapers["a4"]
+	if p, ok := pdfPapers[opts.Paper]; ok {
+		paper = p
+	}
+	cols, rows := float64(max(c.Width(), 1)), float64(max(len(c.Rows), 1))
+	l := pdfLayout{pageW: paper[0], pageH: paper[1], pages: 1}
+	if opts.Poster || cols*pdfCharWidth > rows*pdfLeading {
+		l.pageW, l.pageH = l.pageH, l.pageW
+	}
+	boxW, boxH := l.pageW-2*pdfMargin, l.pageH-2*pdfMargin
+
+	if !opts.Poster {
+		l.size = min(boxW/(cols*pdfCharWidth), boxH/(rows*pdfLeading), pdfMaxFitSize)
+		return l
+	}
+	l.size = boxH / (rows * pdfLeading)
+	l.step = boxW - pdfOverlap
+	if artW := cols * pdfCharWidth * l.size; artW > boxW {
+		l.pages = int(math.Ceil((artW - pdfOverlap) / l.step))
+	}
+	return l
+}
+
+// usesAttr reports whether any cell of the art has the attribute
+func usesAttr(c *canvas.Canvas, a canvas.Attr) bool {
+	for _, r := range c.Rows {
+		for _, cell := range r.Cells {
+			if cell.Style.Attrs&a != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pdfPage draws the part of the art that falls on one page
+func pdfPage(c *canvas.Canvas, opts Options, l pdfLayout, page int) string {
+	var b strings.Builder
+	boxW, boxH := l.pageW-2*pdfMargin, l.pageH-2*pdfMargin
+	paper := rasterPaper
+	if opts.Background.Valid() {
+		paper = opts.Background
+		fmt.Fprintf(&b, "%s rg 0 0 %s %s re f\n", pdfRGB(paper), pdfNum(l.pageW), pdfNum(l.pageH))
+	}
+
+	// Clip to the printable box and shift the art left for later poster pages
+	fmt.Fprintf(&b, "q %d %d %s %s re W n\n", pdfMargin, pdfMargin, pdfNum(boxW), pdfNum(boxH))
+	left := pdfMargin - float64(page)*l.step
+	cellW, lineH := l.size*pdfCharWidth, l.size*pdfLeading
+	for y, r := range c.Rows {
+		bottom := l.pageH - pdfMargin - float64(y+1)*lineH
+		for _, run := range r.Runs() {
+			x := left + float64(run.Col)*cellW
+			width := float64(len(run.Text)) * cellW
+			if x > l.pageW || x+width < 0 {
+				continue
+			}
+			fg, bg := rasterColors(run.Style, paper)
+			if bg.Valid() {
+				fmt.Fprintf(&b, "%s rg %s %s %s %s re f\n", pdfRGB(bg), pdfNum(x), pdfNum(bottom), pdfNum(width), pdfNum(lineH))
+			}
+			if strings.TrimSpace(run.Text) == "" {
+				continue
+			}
+			if run.Style.Attrs&canvas.Dim != 0 {
+				base := paper
+				if bg.Valid() {
+					base = bg
+				}
+				fg = color.Mix(base, fg, 0.6)
+			}
+			font := "F1"
+			if run.Style.Attrs&canvas.Bold != 0 {
+				font = "F2"
+			}
+			baseline := bottom + l.size*pdfDescent
+			fmt.Fprintf(&b, "BT /%s %s Tf %s rg %s %s Td (%s) Tj ET\n",
+				font, pdfNum(l.size), pdfRGB(fg), pdfNum(x), pdfNum(baseline), pdfString(run.Text))
+			if run.Style.Attrs&canvas.Underline != 0 {
+				fmt.Fprintf(&b, "%s RG %s w %s %s m %s %s l S\n", pdfRGB(fg), pdfNum(l.size/15),
+					pdfNum(x), pdfNum(baseline-l.size*0.1), pdfNum(x+width), pdfNum(baseline-l.size*0.1))
+			}
+		}
+	}
+	b.WriteString("Q\n")
+
+	if l.pages > 1 {
+		pdfPosterMarks(&b, l, page)
+	}
+	return b.String()
+}
+
+// pdfPosterMarks draws dashed lines where the neighbouring pages overlap
+// this one, so the sheets can be trimmed and glued edge to edge, and numbers
+// the page in the bottom margin
+func pdfPosterMarks(b *strings.Builder, l pdfLayout, page int) {
+	top, bottom := l.pageH-pdfMargin/2, float64(pdfMargin)/2
+	b.WriteString("q 0.5 G 0.5 w [4 3] 0 d\n")
+	if page > 0 {
+		x := float64(pdfMargin + pdfOverlap)
+		fmt.Fprintf(b, "%s %s m %s %s l S\n", pdfNum(x), pdfNum(bottom), pdfNum(x), pdfNum(top))
+	}
+	if page < l.pages-1 {
+		x := pdfMargin + l.step
+		fmt.Fprintf(b, "%s %s m %s %s l S\n", pdfNum(x), pdfNum(bottom), pdfNum(x), pdfNum(top))
+	}
+	fmt.Fprintf(b, "Q\nBT /F1 8 Tf 0.5 g %d %s Td (%d / %d) Tj ET\n", pdfMargin, pdfNum(bottom-4), page+1, l.pages)
+}
+
+// pdfWriter collects numbered objects and builds the cross-reference table
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (p *pdfWriter) object(body string) {
+	if p.buf.Len() == 0 {
+		p.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	}
+	p.offsets = append(p.offsets, p.buf.Len())
+	fmt.Fprintf(&p.buf, "%d 0 obj\n%s\nendobj\n", len(p.offsets), body)
+}
+
+func (p *pdfWriter) finish() []byte {
+	xref := p.buf.Len()
+	fmt.Fprintf(&p.buf, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, off := range p.offsets {
+		fmt.Fprintf(&p.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&p.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, xref)
+	return p.buf.Bytes()
+}
+
+// pdfString escapes text for a PDF literal string
+func pdfString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(s)
+}
+
+// pdfRGB formats a colour as PDF colour operands
+func pdfRGB(c color.Color) string {
+	return fmt.Sprintf("%s %s %s", pdfNum(float64(c.R)/255), pdfNum(float64(c.G)/255), pdfNum(float64(c.B)/255))
+}
+
+// pdfNum formats a number with at most three decimals
+func pdfNum(f float64) string {
+	s := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.3f", f), "0"), ".")
+	if s == "" || s == "-0" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,189 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+)
+
+// checkXref verifies that every entry of the cross-reference table points
+// at its object and that startxref points at the table
+func checkXref(t *testing.T, pdf []byte, objects int) {
+	t.Helper()
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatalf("no startxref at the end of %q", pdf[max(0, len(pdf)-64):])
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+	lines := strings.SplitAfter(string(pdf[xref:]), "\n")
+	if want := fmt.Sprintf("0 %d\n", objects+1); lines[1] != want {
+		t.Fatalf("xref header = %q, want %q", lines[1], want)
+	}
+	if lines[2] != "0000000000 65535 f \n" {
+		t.Errorf("free entry = %q", lines[2])
+	}
+	for n := 1; n <= objects; n++ {
+		entry := lines[2+n]
+		if len(entry) != 20 || !strings.HasSuffix(entry, " 00000 n \n") {
+			t.Fatalf("entry %d = %q, want 20 bytes ending in an in-use marker", n, entry)
+		}
+		off, _ := strconv.Atoi(entry[:10])
+		if want := fmt.Sprintf("%d 0 obj\n", n); !bytes.HasPrefix(pdf[off:], []byte(want)) {
+			t.Errorf("entry %d points at %q, want %q", n, pdf[off:min(len(pdf), off+len(want))], want)
+		}
+	}
+	if want := fmt.Sprintf("/Size %d ", objects+1); !bytes.Contains(pdf, []byte(want)) {
+		t.Errorf("trailer lacks %q", want)
+	}
+}
+
+func TestPDFWriterXref(t *testing.T) {
+	for _, bodies := range [][]string{
+		{"<< /Type /Catalog >>"},
+		{"<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [] /Count 0 >>", "(a\nmulti-line\nbody)"},
+	} {
+		var p pdfWriter
+		for _, b := range bodies {
+			p.object(b)
+		}
+		pdf := p.finish()
+		if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) {
+			t.Errorf("missing header: %q", pdf[:16])
+		}
+		checkXref(t, pdf, len(bodies))
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	art := canvas.FromText(strings.Repeat(strings.Repeat("#", 300)+"\n", 8))
+	tests := []struct {
+		name  string
+		opts  Options
+		pages int
+	}{
+		{"fits one page", Options{Paper: "a4"}, 1},
+		{"poster", Options{Paper: "letter", Poster: true}, layoutPDF(art, Options{Paper: "letter", Poster: true}).pages},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writePDF(&buf, art, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			pdf := buf.Bytes()
+			objects := bytes.Count(pdf, []byte(" 0 obj\n"))
+			checkXref(t, pdf, objects)
+			if got := bytes.Count(pdf, []byte("/Type /Page ")); got != tt.pages {
+				t.Errorf("%d pages, want %d", got, tt.pages)
+			}
+		})
+	}
+	if got := layoutPDF(art, Options{Paper: "letter", Poster: true}).pages; got < 2 {
+		t.Errorf("a 300-column poster takes %d page, want several", got)
+	}
+}
+
+func TestWritePDFEmbedsFonts(t *testing.T) {
+	plain := canvas.FromText("Hi")
+	bold := canvas.FromText("Hi")
+	bold.Rows[0].Cells[0].Style.Attrs |= canvas.Bold
+	for _, tt := range []struct {
+		name  string
+		art   *canvas.Canvas
+		fonts []string
+	}{
+		{"plain", plain, []string{"GoMono"}},
+		{"bold", bold, []string{"GoMono", "GoMono-Bold"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writePDF(&buf, tt.art, Options{Paper: "a4"}); err != nil {
+				t.Fatal(err)
+			}
+			pdf := buf.Bytes()
+			checkXref(t, pdf, bytes.Count(pdf, []byte(" 0 obj\n")))
+			if got := bytes.Count(pdf, []byte("/FontFile2 ")); got != len(tt.fonts) {
+				t.Errorf("%d embedded fonts, want %d", got, len(tt.fonts))
+			}
+			for _, name := range tt.fonts {
+				if !bytes.Contains(pdf, []byte("/BaseFont /"+name+" ")) {
+					t.Errorf("no font dictionary for %s", name)
+				}
+			}
+			m := regexp.MustCompile(`/Length (\d+) /Length1 (\d+) /Filter /FlateDecode >>\nstream\n`).FindSubmatchIndex(pdf)
+			if m == nil {
+				t.Fatal("no font file stream")
+			}
+			n, _ := strconv.Atoi(string(pdf[m[2]:m[3]]))
+			zr, err := zlib.NewReader(bytes.NewReader(pdf[m[1] : m[1]+n]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ttf, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(ttf, goMonoTTF) {
+				t.Errorf("the font file stream inflates to %d bytes that are not Go Mono", len(ttf))
+			}
+			if length1 := string(pdf[m[4]:m[5]]); length1 != strconv.Itoa(len(ttf)) {
+				t.Errorf("/Length1 %s, want %d", length1, len(ttf))
+			}
+		})
+	}
+}
+
+func TestParsePDFFont(t *testing.T) {
+	for _, f := range []*pdfFont{pdfRegular, pdfBold} {
+		if f.advance != 600 {
+			t.Errorf("%s advance = %d, want 600", f.name, f.advance)
+		}
+		if f.ascent <= 0 || f.descent >= 0 || f.capHeight <= 0 {
+			t.Errorf("%s metrics ascent %d descent %d cap height %d", f.name, f.ascent, f.descent, f.capHeight)
+		}
+	}
+	if pdfBold.stemV <= pdfRegular.stemV {
+		t.Errorf("bold stem %d is not wider than regular %d", pdfBold.stemV, pdfRegular.stemV)
+	}
+	for _, ttf := range [][]byte{nil, []byte("not a font at all"), goMonoTTF[:200]} {
+		if _, err := parsePDFFont("broken", ttf); err == nil {
+			t.Errorf("parsePDFFont(%d bytes) accepted a broken font", len(ttf))
+		}
+	}
+}
+
+func TestPDFNum(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0"},
+		{1, "1"},
+		{0.5, "0.5"},
+		{1.23456, "1.235"},
+		{-0.0001, "0"},
+		{-2.5, "-2.5"},
+		{100, "100"},
+	}
+	for _, tt := range tests {
+		if got := pdfNum(tt.in); got != tt.want {
+			t.Errorf("pdfNum(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPDFString(t *testing.T) {
+	if got, want := pdfString(`a(b)\c`), `a\(b\)\\c`; got != want {
+		t.Errorf("pdfString = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Go Mono, the monospaced font of the Go project (see fonts/README), is
+// embedded in every PDF so that viewers draw the art with the same glyphs
+// and widths instead of substituting a Courier of their own.
+var (
+	//go:embed fonts/Go-Mono.ttf
+	goMonoTTF []byte
+	//go:embed fonts/Go-Mono-Bold.ttf
+	goMonoBoldTTF []byte
+
+	pdfRegular = mustParsePDFFont("GoMono", goMonoTTF)
+	pdfBold    = mustParsePDFFont("GoMono-Bold", goMonoBoldTTF)
+)
+
+// Character codes the font objects describe: printable ASCII and the
+// upper half of WinAnsiEncoding, which art read from .ans files may use
+const (
+	pdfFirstChar = 32
+	pdfLastChar  = 255
+)
+
+// pdfFont is a TrueType font ready to embed, with its metrics in PDF glyph
+// space (thousandths of an em)
+type pdfFont struct {
+	name       string // PostScript name
+	file       []byte // zlib-compressed font program
+	size       int    // uncompressed length
+	advance    int    // width of every glyph
+	bbox       [4]int
+	ascent     int
+	descent    int
+	capHeight  int
+	stemV      int
+	unitsPerEm int
+}
+
+// mustParsePDFFont reads an embedded font; it only fails on a broken build
+func mustParsePDFFont(name string, ttf []byte) *pdfFont {
+	f, err := parsePDFFont(name, ttf)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// parsePDFFont reads the metrics a PDF font descriptor needs from the head,
+// hhea, hmtx, OS/2 and post tables of a monospaced TrueType font
+func parsePDFFont(name string, ttf []byte) (*pdfFont, error) {
+	tables, err := ttfTables(ttf)
+	if err != nil {
+		return nil, fmt.Errorf("font %s: %w", name, err)
+	}
+	for tag, size := range map[string]int{"head": 54, "hhea": 36, "hmtx": 4, "OS/2": 90, "post": 16} {
+		if len(tables[tag]) < size {
+			return nil, fmt.Errorf("font %s: %s table missing or short", name, tag)
+		}
+	}
+	u16 := func(tag string, off int) int { return int(binary.BigEndian.Uint16(tables[tag][off:])) }
+	i16 := func(tag string, off int) int { return int(int16(binary.BigEndian.Uint16(tables[tag][off:]))) }
+
+	if binary.BigEndian.Uint32(tables["post"][12:]) == 0 {
+		return nil, fmt.Errorf("font %s is not monospaced", name)
+	}
+	f := &pdfFont{name: name, size: len(ttf), unitsPerEm: u16("head", 18)}
+	if f.unitsPerEm == 0 {
+		return nil, fmt.Errorf("font %s: no units per em", name)
+	}
+	scale := func(v int) int { return v * 1000 / f.unitsPerEm }
+	f.advance = scale(u16("hmtx", 0))
+	f.bbox = [4]int{scale(i16("head", 36)), scale(i16("head", 38)), scale(i16("head", 40)), scale(i16("head", 42))}
+	f.ascent, f.descent = scale(i16("hhea", 4)), scale(i16("hhea", 6))
+	f.capHeight = scale(i16("OS/2", 88))
+	// the usual estimate of the vertical stem width from the weight class
+	f.stemV = 10 + 220*(u16("OS/2", 4)-50)/900
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(ttf)
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	f.file = buf.Bytes()
+	return f, nil
+}
+
+// ttfTables indexes the tables of a TrueType file by tag
+func ttfTables(ttf []byte) (map[string][]byte, error) {
+	if len(ttf) < 12 {
+		return nil, fmt.Errorf("not a TrueType file")
+	}
+	n := int(binary.BigEndian.Uint16(ttf[4:]))
+	if len(ttf) < 12+16*n {
+		return nil, fmt.Errorf("truncated table directory")
+	}
+	tables := make(map[string][]byte, n)
+	for i := 0; i < n; i++ {
+		entry := ttf[12+16*i:]
+		off, size := binary.BigEndian.Uint32(entry[8:]), binary.BigEndian.Uint32(entry[12:])
+		if uint64(off)+uint64(size) > uint64(len(ttf)) {
+			return nil, fmt.Errorf("table %q runs past the end of the file", entry[:4])
+		}
+		tables[string(entry[:4])] = ttf[off : off+size]
+	}
+	return tables, nil
+}
+
+// charWidth is the advance of every glyph, in em
+func (f *pdfFont) charWidth() float64 {
+	return float64(f.advance) / 1000
+}
+
+// objects returns the font dictionary, descriptor and font file stream,
+// numbered from first
+func (f *pdfFont) objects(first int) []string {
+	widths := strings.TrimSpace(strings.Repeat(fmt.Sprintf("%d ", f.advance), pdfLastChar-pdfFirstChar+1))
+	return []string{
+		fmt.Sprintf("<< /Type /Font /Subtype /TrueType /BaseFont /%s /FirstChar %d /LastChar %d /Widths [%s] "+
+			"/FontDescriptor %d 0 R /Encoding /WinAnsiEncoding >>", f.name, pdfFirstChar, pdfLastChar, widths, first+1),
+		// Flags 33: fixed pitch, and the Latin character set
+		fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 33 /FontBBox [%d %d %d %d] /ItalicAngle 0 "+
+			"/Ascent %d /Descent %d /CapHeight %d /StemV %d /FontFile2 %d 0 R >>",
+			f.name, f.bbox[0], f.bbox[1], f.bbox[2], f.bbox[3], f.ascent, f.descent, f.capHeight, f.stemV, first+2),
+		fmt.Sprintf("<< /Length %d /Length1 %d /Filter /FlateDecode >>\nstream\n%s\nendstream", len(f.file), f.size, f.file),
+	}
+}
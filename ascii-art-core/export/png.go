@@ -0,0 +1,134 @@
+package export
+
+import (
+	"image"
+	icolor "image/color"
+	"image/draw"
+	"image/png"
+	"io"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+func init() {
+	Register(&Exporter{
+		Name:        "png",
+		Ext:         "png",
+		ContentType: "image/png",
+		Write:       writePNG,
+		Frames:      oneFrame,
+		Raster:      true,
+	})
+}
+
+// Default raster settings: the built-in font at twice its size, black on white
+const (
+	defaultCellW = glyphW * 2
+	defaultCellH = glyphH * 2
+)
+
+var (
+	rasterInk   = color.RGB(0, 0, 0)
+	rasterPaper = color.RGB(255, 255, 255)
+)
+
+// writePNG rasterizes the canvas with the built-in bitmap font
+func writePNG(w io.Writer, c *canvas.Canvas, opts Options) error {
+	return png.Encode(w, rasterize(c, opts))
+}
+
+// cellSize returns the pixel size of a cell, with the defaults filled in
+func (opts Options) cellSize() (int, int) {
+	cw, ch := opts.CellW, opts.CellH
+	if cw <= 0 {
+		cw = defaultCellW
+	}
+	if ch <= 0 {
+		ch = defaultCellH
+	}
+	return cw, ch
+}
+
+// rasterize draws every cell of the canvas into an image, one CellW x CellH
+// box per cell. Glyphs are scaled by whole pixels and centred in the box.
+func rasterize(c *canvas.Canvas, opts Options) *image.RGBA {
+	cw, ch := opts.cellSize()
+	scale := min(cw/glyphW, ch/glyphH)
+	if scale < 1 {
+		scale = 1
+	}
+	offX, offY := (cw-glyphW*scale)/2, (ch-glyphH*scale)/2
+
+	paper := rasterPaper
+	if opts.Background.Valid() {
+		paper = opts.Background
+	}
+	img := image.NewRGBA(image.Rect(0, 0, max(c.Width(), 1)*cw, max(len(c.Rows), 1)*ch))
+	if !opts.Transparent {
+		draw.Draw(img, img.Bounds(), image.NewUniform(rgba(paper)), image.Point{}, draw.Src)
+	}
+
+	for y, r := range c.Rows {
+		for x, cell := range r.Cells {
+			fg, bg := rasterColors(cell.Style, paper)
+			box := image.Rect(x*cw, y*ch, (x+1)*cw, (y+1)*ch)
+			if bg.Valid() {
+				draw.Draw(img, box, image.NewUniform(rgba(bg)), image.Point{}, draw.Src)
+			}
+			if cell.Style.Attrs&canvas.Dim != 0 {
+				base := paper
+				if bg.Valid() {
+					base = bg
+				}
+				fg = color.Mix(base, fg, 0.6)
+			}
+			ink := rgba(fg)
+			glyph := glyphRows(cell.Ch)
+			if cell.Style.Attrs&canvas.Underline != 0 {
+				glyph[glyphH-1] = 0x3F
+			}
+			for gy, bits := range glyph {
+				for gx := 0; gx < glyphW; gx++ {
+					on := bits&(0x20>>gx) != 0
+					// Bold smears each pixel one step to the right
+					if !on && cell.Style.Attrs&canvas.Bold != 0 && gx > 0 {
+						on = bits&(0x20>>(gx-1)) != 0
+					}
+					if !on {
+						continue
+					}
+					px := box.Min.X + offX + gx*scale
+					py := box.Min.Y + offY + gy*scale
+					draw.Draw(img, image.Rect(px, py, px+scale, py+scale), image.NewUniform(ink), image.Point{}, draw.Src)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// rasterColors returns the ink and the cell background for a style; the
+// background is left unset when the cell shows the page through. Unstyled
+// text is black, or white on a dark page.
+func rasterColors(s canvas.Style, paper color.Color) (fg, bg color.Color) {
+	fg, bg = s.FG, s.BG
+	if !fg.Valid() {
+		fg = rasterInk
+		if isDark(paper) {
+			fg = rasterPaper
+		}
+	}
+	if s.Attrs&canvas.Reverse != 0 {
+		if !bg.Valid() {
+			bg = paper
+		}
+		fg, bg = bg, fg
+	}
+	return fg, bg
+}
+
+// rgba converts a colour to its image/color form
+func rgba(c color.Color) icolor.RGBA {
+	return icolor.RGBA{R: c.R, G: c.G, B: c.B, A: 255}
+}
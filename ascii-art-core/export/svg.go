@@ -0,0 +1,148 @@
+package export
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+)
+
+func init() {
+	Register(&Exporter{
+		Name:        "svg",
+		Ext:         "svg",
+		ContentType: "image/svg+xml",
+		Write:       writeSVG,
+	})
+}
+
+// Grid metrics of the SVG in user units. Every run is placed at its own x, so
+// columns line up even when the viewer's monospace font is a little narrower
+// or wider than 0.6em.
+const (
+	svgFontSize = 14
+	svgCellW    = 8.4
+	svgCellH    = 17
+	svgFonts    = "'DejaVu Sans Mono', Menlo, Consolas, 'Courier New', monospace"
+)
+
+// writeSVG draws the canvas with one <text> per row, or with filled rects
+// per cell when opts.SVGPixels is set
+func writeSVG(w io.Writer, c *canvas.Canvas, opts Options) error {
+	var b strings.Builder
+	width, height := num(float64(c.Width())*svgCellW), num(float64(len(c.Rows))*svgCellH)
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&b, `<g font-family="%s" font-size="%d">`+"\n", svgFonts, svgFontSize)
+
+	for y, r := range c.Rows {
+		top := float64(y) * svgCellH
+		for _, run := range r.Runs() {
+			_, bg := svgColors(run.Style)
+			if bg != "" {
+				fmt.Fprintf(&b, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`+"\n",
+					num(float64(run.Col)*svgCellW), num(top), num(float64(len(run.Text))*svgCellW), num(svgCellH), bg)
+			}
+		}
+		if opts.SVGPixels {
+			svgPixelRow(&b, r, top)
+		} else {
+			svgTextRow(&b, r, top)
+		}
+	}
+	b.WriteString("</g>\n</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// svgTextRow writes a row as a <text> with one <tspan> per coloured run
+func svgTextRow(b *strings.Builder, r canvas.Row, top float64) {
+	var spans strings.Builder
+	for _, run := range r.Runs() {
+		if strings.TrimSpace(run.Text) == "" {
+			continue
+		}
+		spans.WriteString(`<tspan x="` + num(float64(run.Col)*svgCellW) + `"`)
+		if fg, _ := svgColors(run.Style); fg != "" {
+			spans.WriteString(` fill="` + fg + `"`)
+		}
+		if run.Style.Attrs&canvas.Bold != 0 {
+			spans.WriteString(` font-weight="bold"`)
+		}
+		if run.Style.Attrs&canvas.Italic != 0 {
+			spans.WriteString(` font-style="italic"`)
+		}
+		if run.Style.Attrs&canvas.Underline != 0 {
+			spans.WriteString(` text-decoration="underline"`)
+		}
+		if run.Style.Attrs&canvas.Dim != 0 {
+			spans.WriteString(` fill-opacity="0.6"`)
+		}
+		spans.WriteString(">" + html.EscapeString(run.Text) + "</tspan>")
+	}
+	if spans.Len() == 0 {
+		return
+	}
+	// The baseline sits about four fifths of the way down the cell
+	fmt.Fprintf(b, `<text y="%s" xml:space="preserve">%s</text>`+"\n", num(top+svgCellH*0.8), spans.String())
+}
+
+// svgPixelRow fills every non-space cell of a row with its foreground colour.
+// Neighbouring cells of the same colour share one rect so no seams show
+// between them when the image is scaled.
+func svgPixelRow(b *strings.Builder, r canvas.Row, top float64) {
+	for x := 0; x < len(r.Cells); {
+		if r.Cells[x].Ch == ' ' {
+			x++
+			continue
+		}
+		fill, _ := svgColors(r.Cells[x].Style)
+		if fill == "" {
+			fill = "#000000"
+		}
+		end := x + 1
+		for end < len(r.Cells) && r.Cells[end].Ch != ' ' {
+			next, _ := svgColors(r.Cells[end].Style)
+			if next == "" {
+				next = "#000000"
+			}
+			if next != fill {
+				break
+			}
+			end++
+		}
+		fmt.Fprintf(b, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`+"\n",
+			num(float64(x)*svgCellW), num(top), num(float64(end-x)*svgCellW), num(svgCellH), fill)
+		x = end
+	}
+}
+
+// svgColors returns the fill and background of a style, swapped for reverse
+// video. Default text is black on a transparent background.
+func svgColors(s canvas.Style) (fg, bg string) {
+	if s.FG.Valid() {
+		fg = s.FG.Hex()
+	}
+	if s.BG.Valid() {
+		bg = s.BG.Hex()
+	}
+	if s.Attrs&canvas.Reverse != 0 {
+		if fg == "" {
+			fg = "#000000"
+		}
+		if bg == "" {
+			bg = "#ffffff"
+		}
+		fg, bg = bg, fg
+	}
+	return fg, bg
+}
+
+// num formats a coordinate to two decimals without trailing zeros
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,34 @@
+package export
+
+import (
+	"io"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+)
+
+func init() {
+	Register(&Exporter{
+		Name:        "ansi",
+		Ext:         "ans",
+		ContentType: "text/plain; charset=utf-8",
+		Write:       writeANSI,
+	})
+	Register(&Exporter{
+		Name:        "txt",
+		Ext:         "txt",
+		ContentType: "text/plain; charset=utf-8",
+		Write:       writeText,
+	})
+}
+
+// writeANSI keeps every colour and attribute as terminal escape codes
+func writeANSI(w io.Writer, c *canvas.Canvas, opts Options) error {
+	_, err := io.WriteString(w, c.ANSI(opts.TrueColor))
+	return err
+}
+
+// writeText writes the bare characters
+func writeText(w io.Writer, c *canvas.Canvas, _ Options) error {
+	_, err := io.WriteString(w, c.Plain())
+	return err
+}
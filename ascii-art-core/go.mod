@@ -0,0 +1,3 @@
+module platform.zone01.gr/git/askordal/ascii-art-core
+
+go 1.22
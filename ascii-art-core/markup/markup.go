@@ -0,0 +1,220 @@
+// Package markup parses the inline style language that mixes colours,
+// attributes, banners and alignment in one string:
+//
+//	{red}Deploy{/} {bold,font=shadow}OK{/}
+//
+// A tag "{item,item,...}" opens a span that lasts until the matching "{/}";
+// spans nest and inherit from the enclosing one. Items are:
+//
+//	red, #ff0000, red/white   foreground (and background) colour
+//	fg=<color>, bg=<color>    the same, spelled out
+//	bold, dim, italic, underline, blink, reverse
+//	font=<banner>             banner used for the span
+//	align=<left|center|right|justify>
+//	                          alignment of the lines the span starts on
+//
+// "\{", "\}" and "\\" write a literal brace or backslash.
+package markup
+
+import (
+	"fmt"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+// Result is the text with its markup removed plus what the markup asked for.
+type Result struct {
+	Text   string
+	Styles []canvas.Style // style of each byte of Text; zero where no span applies
+	Fonts  []string       // banner of each byte of Text; "" for the default banner
+	Aligns []string       // alignment of each line of Text; "" for the default
+}
+
+// span is the state a tag sets up
+type span struct {
+	style canvas.Style
+	font  string
+	align string
+}
+
+var validAligns = map[string]bool{"left": true, "center": true, "right": true, "justify": true}
+
+// Parse strips the markup from src.
+func Parse(src string) (*Result, error) {
+	res := &Result{Aligns: []string{""}}
+	var text strings.Builder
+	stack := []span{{}}
+
+	for i := 0; i < len(src); i++ {
+		cur := stack[len(stack)-1]
+		ch := src[i]
+
+		switch {
+		case ch == '\\' && i+1 < len(src) && strings.IndexByte(`{}\`, src[i+1]) >= 0:
+			i++
+			ch = src[i]
+
+		case ch == '{':
+			end := strings.IndexByte(src[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("markup: unclosed tag at position %d (write \\{ for a literal brace)", i+1)
+			}
+			tag := src[i+1 : i+end]
+			i += end
+			if tag == "/" {
+				if len(stack) == 1 {
+					return nil, fmt.Errorf("markup: {/} at position %d has no open tag", i-end+1)
+				}
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			next, err := cur.open(tag)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, next)
+			// an alignment applies to the line the span starts on
+			if next.align != "" {
+				res.Aligns[len(res.Aligns)-1] = next.align
+			}
+			continue
+
+		case ch == '}':
+			return nil, fmt.Errorf("markup: unexpected } at position %d (write \\} for a literal brace)", i+1)
+		}
+
+		if ch == '\n' {
+			res.Aligns = append(res.Aligns, cur.align)
+		}
+		text.WriteByte(ch)
+		res.Styles = append(res.Styles, cur.style)
+		res.Fonts = append(res.Fonts, cur.font)
+	}
+
+	res.Text = text.String()
+	return res, nil
+}
+
+// open returns the state of a span nested in s with the given tag
+func (s span) open(tag string) (span, error) {
+	items := splitItems(tag)
+	if len(items) == 0 {
+		return s, fmt.Errorf("markup: empty tag {}")
+	}
+	for _, item := range items {
+		key, value, isPair := strings.Cut(item, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch {
+		case isPair && (key == "font" || key == "banner"):
+			s.font = strings.TrimSuffix(value, ".txt")
+
+		case isPair && key == "align":
+			if !validAligns[value] {
+				return s, fmt.Errorf("markup: invalid alignment %q", value)
+			}
+			s.align = value
+
+		case isPair && (key == "fg" || key == "color"):
+			c, err := color.Parse(value)
+			if err != nil {
+				return s, fmt.Errorf("markup: %w", err)
+			}
+			s.style.FG = c
+
+		case isPair && key == "bg":
+			c, err := color.Parse(value)
+			if err != nil {
+				return s, fmt.Errorf("markup: %w", err)
+			}
+			s.style.BG = c
+
+		case isPair:
+			return s, fmt.Errorf("markup: unknown setting %q", key)
+
+		default:
+			if a, err := canvas.ParseAttr(item); err == nil {
+				s.style.Attrs |= a
+				continue
+			}
+			if err := s.applyColors(item); err != nil {
+				return s, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// applyColors reads an item written as "fg", "fg/bg" or "/bg"
+func (s *span) applyColors(item string) error {
+	fg, bg, attrs, err := canvas.SplitStyle(item)
+	if err != nil {
+		return fmt.Errorf("markup: %w", err)
+	}
+	if fg != "" {
+		c, err := color.Parse(fg)
+		if err != nil {
+			return fmt.Errorf("markup: unknown item %q", item)
+		}
+		s.style.FG = c
+	}
+	if bg != "" {
+		c, err := color.Parse(bg)
+		if err != nil {
+			return fmt.Errorf("markup: %w", err)
+		}
+		s.style.BG = c
+	}
+	s.style.Attrs |= attrs
+	return nil
+}
+
+// splitItems splits a tag on commas that are not inside parentheses,
+// so "rgb(1,2,3),bold" gives two items
+func splitItems(tag string) []string {
+	var items []string
+	depth, start := 0, 0
+	for i := 0; i <= len(tag); i++ {
+		if i < len(tag) {
+			switch tag[i] {
+			case '(':
+				depth++
+				continue
+			case ')':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		if item := strings.TrimSpace(tag[start:i]); item != "" {
+			items = append(items, item)
+		}
+		start = i + 1
+	}
+	return items
+}
+
+// Merge lays the markup styles over styles computed by other colour rules;
+// colours the markup sets win and attributes are combined.
+func Merge(styles, over []canvas.Style) {
+	for i := range styles {
+		if i >= len(over) {
+			return
+		}
+		if over[i].FG.Valid() {
+			styles[i].FG = over[i].FG
+		}
+		if over[i].BG.Valid() {
+			styles[i].BG = over[i].BG
+		}
+		styles[i].Attrs |= over[i].Attrs
+	}
+}
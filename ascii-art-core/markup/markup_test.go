@@ -0,0 +1,79 @@
+package markup
+
+import (
+	"reflect"
+	"testing"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+func TestParse(t *testing.T) {
+	fg, err := color.Parse("red")
+	if err != nil {
+		t.Fatal(err)
+	}
+	red := canvas.Style{FG: fg}
+	redBold := canvas.Style{FG: red.FG, Attrs: canvas.Bold}
+	tests := []struct {
+		name   string
+		src    string
+		text   string
+		styles []canvas.Style
+	}{
+		{"plain text", "ab", "ab", []canvas.Style{{}, {}}},
+		{"span", "{red}a{/}b", "ab", []canvas.Style{red, {}}},
+		{"nested spans inherit", "{red}a{bold}b{/}c{/}", "abc", []canvas.Style{red, redBold, red}},
+		{"unclosed span runs to the end", "a{red}b", "ab", []canvas.Style{{}, red}},
+		{"escaped braces", `\{a\}`, "{a}", []canvas.Style{{}, {}, {}}},
+		{"escaped backslash", `\\{red}a{/}`, `\a`, []canvas.Style{{}, red}},
+		{"backslash before other text", `\n`, `\n`, []canvas.Style{{}, {}}},
+		{"commas inside colour functions", "{rgb(255,0,0),bold}a{/}", "a",
+			[]canvas.Style{{FG: color.RGB(255, 0, 0), Attrs: canvas.Bold}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Parse(tt.src)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.src, err)
+			}
+			if res.Text != tt.text {
+				t.Errorf("Text = %q, want %q", res.Text, tt.text)
+			}
+			if !reflect.DeepEqual(res.Styles, tt.styles) {
+				t.Errorf("Styles = %v, want %v", res.Styles, tt.styles)
+			}
+		})
+	}
+}
+
+func TestParseFontsAndAligns(t *testing.T) {
+	res, err := Parse("a{font=shadow.txt,align=center}b\nc{/}\nd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"", "shadow", "shadow", "shadow", "", ""}; !reflect.DeepEqual(res.Fonts, want) {
+		t.Errorf("Fonts = %q, want %q", res.Fonts, want)
+	}
+	if want := []string{"center", "center", ""}; !reflect.DeepEqual(res.Aligns, want) {
+		t.Errorf("Aligns = %q, want %q", res.Aligns, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"{red}a{/}{/}",
+		"{/}",
+		"{red",
+		"a}",
+		"{}",
+		"{nocolor}a",
+		"{align=middle}a",
+		"{size=3}a",
+		"{bg=nocolor}a",
+	} {
+		if _, err := Parse(src); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", src)
+		}
+	}
+}
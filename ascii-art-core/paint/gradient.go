@@ -0,0 +1,140 @@
+// Package paint applies colour effects to a rendered canvas.
+package paint
+
+import (
+	"fmt"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+// Direction selects what a gradient runs across.
+type Direction int
+
+const (
+	Columns Direction = iota // left to right across the rendered width
+	Rows                     // top to bottom down the glyph rows
+	Chars                    // one colour per input character
+)
+
+// ParseDirection converts "column", "row" or "char" into a Direction.
+func ParseDirection(s string) (Direction, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "column", "columns", "col", "horizontal":
+		return Columns, nil
+	case "row", "rows", "vertical":
+		return Rows, nil
+	case "char", "chars", "character", "characters":
+		return Chars, nil
+	}
+	return Columns, fmt.Errorf("invalid gradient direction %q (use column, row or char)", s)
+}
+
+// Gradient blends a list of colour stops over the art.
+type Gradient struct {
+	Stops     []color.Color
+	Direction Direction
+}
+
+// ParseGradient reads stops written as "<color>:<color>[:<color>...]".
+func ParseGradient(spec, direction string) (*Gradient, error) {
+	dir, err := ParseDirection(direction)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("gradient %q needs at least two colors", spec)
+	}
+	g := &Gradient{Direction: dir}
+	for _, p := range parts {
+		c, err := color.Parse(p)
+		if err != nil {
+			return nil, fmt.Errorf("gradient: %w", err)
+		}
+		g.Stops = append(g.Stops, c)
+	}
+	return g, nil
+}
+
+// At returns the gradient colour at position t in [0, 1]. The result is
+// always a plain RGB value, even where it lands on a named terminal colour,
+// so every cell of the gradient is written the same way.
+func (g *Gradient) At(t float64) color.Color {
+	var c color.Color
+	switch {
+	case t <= 0:
+		c = g.Stops[0]
+	case t >= 1:
+		c = g.Stops[len(g.Stops)-1]
+	default:
+		span := t * float64(len(g.Stops)-1)
+		i := int(span)
+		c = color.Mix(g.Stops[i], g.Stops[i+1], span-float64(i))
+	}
+	return color.RGB(c.R, c.G, c.B)
+}
+
+// Apply paints every glyph cell that has no colour yet. Padding added by
+// alignment is left alone and does not stretch the gradient.
+func (g *Gradient) Apply(c *canvas.Canvas) {
+	var pos func(row canvas.Row, x int, cell canvas.Cell) float64
+
+	switch g.Direction {
+	case Rows:
+		pos = func(row canvas.Row, _ int, _ canvas.Cell) float64 {
+			return float64(row.Band) / float64(canvas.BandHeight-1)
+		}
+
+	case Chars:
+		// rank the input characters in the order they were drawn
+		rank := make(map[int]int)
+		for _, row := range c.Rows {
+			for _, cell := range row.Cells {
+				if _, seen := rank[cell.Src]; cell.Src >= 0 && !seen {
+					rank[cell.Src] = len(rank)
+				}
+			}
+		}
+		n := len(rank)
+		pos = func(_ canvas.Row, _ int, cell canvas.Cell) float64 {
+			if n < 2 {
+				return 0
+			}
+			return float64(rank[cell.Src]) / float64(n-1)
+		}
+
+	default:
+		left, right := -1, -1
+		for _, row := range c.Rows {
+			for x, cell := range row.Cells {
+				if cell.Src < 0 {
+					continue
+				}
+				if left < 0 || x < left {
+					left = x
+				}
+				if x > right {
+					right = x
+				}
+			}
+		}
+		pos = func(_ canvas.Row, x int, _ canvas.Cell) float64 {
+			if right <= left {
+				return 0
+			}
+			return float64(x-left) / float64(right-left)
+		}
+	}
+
+	for _, row := range c.Rows {
+		for x := range row.Cells {
+			cell := &row.Cells[x]
+			if cell.Src < 0 || cell.Style.FG.Valid() {
+				continue
+			}
+			cell.Style.FG = g.At(pos(row, x, *cell))
+		}
+	}
+}
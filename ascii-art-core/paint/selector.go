@@ -0,0 +1,215 @@
+package paint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+)
+
+// Selector picks the parts of the input text a colour rule applies to.
+//
+// A plain string matches literally ("\n" stands for a line break). Prefixes
+// change how it matches and can be combined, e.g. "nth:2:icase:word:go":
+//
+//	regex:<re>    regular expression
+//	word:<s>      whole words only
+//	icase:<s>     ignore case
+//	nth:<n>:<s>   only the n-th match
+//	range:<a>-<b> characters a to b, counted from 1 including line breaks;
+//	              "range:4-" runs to the end, "range:4" is a single character
+//	lit:<s>       literal text, for strings that start with one of the prefixes
+type Selector struct {
+	literal  string
+	re       *regexp.Regexp
+	isRange  bool
+	from, to int // range bounds, 0-based and end-exclusive; to < 0 means end of text
+	nth      int // 1-based occurrence to keep, 0 keeps all
+}
+
+// ParseSelector reads a selector written with the syntax described on Selector.
+func ParseSelector(spec string) (*Selector, error) {
+	s := &Selector{}
+	icase, word := false, false
+	rest := spec
+
+	for {
+		switch {
+		case strings.HasPrefix(rest, "icase:"):
+			icase = true
+			rest = strings.TrimPrefix(rest, "icase:")
+			continue
+
+		case strings.HasPrefix(rest, "word:"):
+			word = true
+			rest = strings.TrimPrefix(rest, "word:")
+			continue
+
+		case strings.HasPrefix(rest, "nth:"):
+			num, tail, ok := strings.Cut(strings.TrimPrefix(rest, "nth:"), ":")
+			n, err := strconv.Atoi(num)
+			if !ok || err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid selector %q: nth needs a positive number, e.g. nth:2:Go", spec)
+			}
+			s.nth = n
+			rest = tail
+			continue
+
+		case strings.HasPrefix(rest, "range:"):
+			if icase || word || s.nth > 0 {
+				return nil, fmt.Errorf("invalid selector %q: range cannot be combined with other prefixes", spec)
+			}
+			if err := s.parseRange(strings.TrimPrefix(rest, "range:")); err != nil {
+				return nil, fmt.Errorf("invalid selector %q: %w", spec, err)
+			}
+			return s, nil
+
+		case strings.HasPrefix(rest, "regex:"):
+			rest = strings.TrimPrefix(rest, "regex:")
+			if rest == "" {
+				return nil, fmt.Errorf("invalid selector %q: empty regex", spec)
+			}
+			return s.compile(spec, rest, icase, word)
+
+		case strings.HasPrefix(rest, "lit:"):
+			rest = strings.TrimPrefix(rest, "lit:")
+		}
+		break
+	}
+
+	rest = strings.ReplaceAll(rest, `\n`, "\n")
+	if rest == "" {
+		return nil, fmt.Errorf("invalid selector %q: nothing to match", spec)
+	}
+	if icase || word {
+		return s.compile(spec, regexp.QuoteMeta(rest), icase, word)
+	}
+	s.literal = rest
+	return s, nil
+}
+
+// compile builds the regular expression of a regex, word or icase selector
+func (s *Selector) compile(spec, pattern string, icase, word bool) (*Selector, error) {
+	if word {
+		pattern = `\b(?:` + pattern + `)\b`
+	}
+	if icase {
+		pattern = `(?i)` + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", spec, err)
+	}
+	s.re = re
+	return s, nil
+}
+
+// parseRange reads "a-b", "a-" or "a" (1-based, inclusive)
+func (s *Selector) parseRange(r string) error {
+	from, to, hasTo := strings.Cut(r, "-")
+	a, err := strconv.Atoi(from)
+	if err != nil || a < 1 {
+		return fmt.Errorf("range start must be a number from 1")
+	}
+	s.isRange = true
+	s.from, s.to = a-1, a
+	if !hasTo {
+		return nil
+	}
+	if to == "" {
+		s.to = -1
+		return nil
+	}
+	b, err := strconv.Atoi(to)
+	if err != nil || b < a {
+		return fmt.Errorf("range end must be a number not below the start")
+	}
+	s.to = b
+	return nil
+}
+
+// Find returns the [start, end) byte spans of text the selector matches.
+func (s *Selector) Find(text string) [][2]int {
+	var spans [][2]int
+	switch {
+	case s.isRange:
+		to := s.to
+		if to < 0 || to > len(text) {
+			to = len(text)
+		}
+		if s.from < to {
+			spans = append(spans, [2]int{s.from, to})
+		}
+		return spans
+
+	case s.re != nil:
+		for _, m := range s.re.FindAllStringIndex(text, -1) {
+			if m[1] > m[0] { // an empty match colours nothing
+				spans = append(spans, [2]int{m[0], m[1]})
+			}
+		}
+
+	default:
+		for i := 0; ; {
+			j := strings.Index(text[i:], s.literal)
+			if j < 0 {
+				break
+			}
+			spans = append(spans, [2]int{i + j, i + j + len(s.literal)})
+			i += j + len(s.literal)
+		}
+	}
+
+	if s.nth > 0 {
+		if s.nth > len(spans) {
+			return nil
+		}
+		return spans[s.nth-1 : s.nth]
+	}
+	return spans
+}
+
+// Rule pairs a selector with the style it paints.
+type Rule struct {
+	Selector *Selector
+	Style    canvas.Style
+}
+
+// StyleText returns the style of every byte of text: base, overridden by the
+// rules. Where matches overlap the longest one wins, and between matches of
+// the same length the later rule wins, so "Golang" beats "Go" whatever the
+// order the rules were given in.
+func StyleText(text string, base canvas.Style, rules []Rule) []canvas.Style {
+	styles := make([]canvas.Style, len(text))
+	for i := range styles {
+		styles[i] = base
+	}
+
+	type match struct {
+		from, to, rule int
+	}
+	var matches []match
+	for ri, r := range rules {
+		for _, sp := range r.Selector.Find(text) {
+			matches = append(matches, match{sp[0], sp[1], ri})
+		}
+	}
+
+	// paint the weakest matches first so stronger ones overwrite them
+	sort.SliceStable(matches, func(i, j int) bool {
+		li, lj := matches[i].to-matches[i].from, matches[j].to-matches[j].from
+		if li != lj {
+			return li < lj
+		}
+		return matches[i].rule < matches[j].rule
+	})
+	for _, m := range matches {
+		for i := m.from; i < m.to; i++ {
+			styles[i] = rules[m.rule].Style
+		}
+	}
+	return styles
+}
@@ -0,0 +1,99 @@
+package paint
+
+import (
+	"reflect"
+	"testing"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+func TestSelectorFind(t *testing.T) {
+	tests := []struct {
+		spec string
+		text string
+		want [][2]int
+	}{
+		{"go", "go gopher go", [][2]int{{0, 2}, {3, 5}, {10, 12}}},
+		{"word:go", "go gopher go", [][2]int{{0, 2}, {10, 12}}},
+		{"icase:GO", "Go gO", [][2]int{{0, 2}, {3, 5}}},
+		{"regex:[0-9]+", "a12b3", [][2]int{{1, 3}, {4, 5}}},
+		{"regex:x*", "abc", nil},
+		{"nth:2:go", "go gopher go", [][2]int{{3, 5}}},
+		{"nth:4:go", "go gopher go", nil},
+		{"nth:2:icase:word:go", "Go gopher GO", [][2]int{{10, 12}}},
+		{"range:3-5", "abcdefg", [][2]int{{2, 5}}},
+		{"range:4", "abcdefg", [][2]int{{3, 4}}},
+		{"range:5-", "abcdefg", [][2]int{{4, 7}}},
+		{"range:5-99", "abcdefg", [][2]int{{4, 7}}},
+		{"range:9", "abcdefg", nil},
+		{`a\nb`, "a\nb", [][2]int{{0, 3}}},
+		{"lit:regex:", "x regex:", [][2]int{{2, 8}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := ParseSelector(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSelector(%q): %v", tt.spec, err)
+			}
+			if got := s.Find(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"nth:0:go",
+		"nth:x:go",
+		"nth:2",
+		"range:0-3",
+		"range:5-3",
+		"range:a",
+		"icase:range:1-2",
+		"regex:",
+		"regex:(",
+		"word:",
+	} {
+		if _, err := ParseSelector(spec); err == nil {
+			t.Errorf("ParseSelector(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestStyleText(t *testing.T) {
+	red, blue, green := canvas.Style{FG: color.RGB(255, 0, 0)}, canvas.Style{FG: color.RGB(0, 0, 255)}, canvas.Style{FG: color.RGB(0, 255, 0)}
+	rule := func(spec string, st canvas.Style) Rule {
+		s, err := ParseSelector(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Rule{Selector: s, Style: st}
+	}
+	tests := []struct {
+		name  string
+		text  string
+		rules []Rule
+		want  []canvas.Style
+	}{
+		{"longest match wins", "Golang", []Rule{rule("Golang", red), rule("Go", blue)},
+			[]canvas.Style{red, red, red, red, red, red}},
+		{"longest match wins in either order", "Golang", []Rule{rule("Go", blue), rule("Golang", red)},
+			[]canvas.Style{red, red, red, red, red, red}},
+		{"later rule wins on a tie", "ab", []Rule{rule("ab", red), rule("range:1-2", blue)},
+			[]canvas.Style{blue, blue}},
+		{"longer match wins where they overlap", "abc", []Rule{rule("range:2-3", red), rule("c", blue)},
+			[]canvas.Style{green, red, red}},
+		{"shorter match keeps what it alone covers", "abc", []Rule{rule("a", blue), rule("range:2-3", red)},
+			[]canvas.Style{blue, red, red}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StyleText(tt.text, green, tt.rules); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StyleText(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package paint
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+// Theme is a named palette. User themes are JSON files with the same shape:
+//
+//	{"name": "sunset", "colors": ["#ff5e5b", "#ffed66", "rgb(0,206,203)"]}
+type Theme struct {
+	Name   string   `json:"name"`
+	Colors []string `json:"colors"`
+}
+
+// builtinThemes is the catalog available without any configuration
+var builtinThemes = []Theme{
+	{Name: "dracula", Colors: []string{"#ff79c6", "#bd93f9", "#8be9fd", "#50fa7b", "#ffb86c", "#ff5555", "#f1fa8c"}},
+	{Name: "gruvbox", Colors: []string{"#fb4934", "#b8bb26", "#fabd2f", "#83a598", "#d3869b", "#8ec07c", "#fe8019"}},
+	{Name: "monokai", Colors: []string{"#f92672", "#fd971f", "#e6db74", "#a6e22e", "#66d9ef", "#ae81ff"}},
+	{Name: "nord", Colors: []string{"#88c0d0", "#81a1c1", "#5e81ac", "#bf616a", "#d08770", "#ebcb8b", "#a3be8c", "#b48ead"}},
+	{Name: "rainbow", Colors: []string{"#ff0000", "#ff7f00", "#ffff00", "#00ff00", "#0000ff", "#4b0082", "#8f00ff"}},
+	{Name: "solarized", Colors: []string{"#b58900", "#cb4b16", "#dc322f", "#d33682", "#6c71c4", "#268bd2", "#2aa198", "#859900"}},
+}
+
+// ThemeDir returns the folder user palette files are read from,
+// e.g. ~/.config/ascii-art/themes on Linux.
+func ThemeDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ascii-art", "themes")
+}
+
+// LoadThemes returns the built-in themes plus every *.json palette in dir,
+// sorted by name. A user theme replaces a built-in one with the same name.
+// A missing dir is not an error, and a file that can't be read or holds an
+// invalid palette is skipped; the problem is returned in skipped so the
+// caller can report it.
+func LoadThemes(dir string) (themes []Theme, skipped []error, err error) {
+	byName := make(map[string]Theme)
+	for _, t := range builtinThemes {
+		byName[t.Name] = t
+	}
+
+	if dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not list themes: %w", err)
+		}
+		for _, f := range files {
+			t, err := readTheme(f)
+			if err != nil {
+				skipped = append(skipped, err)
+				continue
+			}
+			byName[t.Name] = t
+		}
+	}
+
+	themes = make([]Theme, 0, len(byName))
+	for _, t := range byName {
+		themes = append(themes, t)
+	}
+	sort.Slice(themes, func(i, j int) bool { return themes[i].Name < themes[j].Name })
+	return themes, skipped, nil
+}
+
+// readTheme loads and validates one palette file; the file name is used
+// when the JSON has no name
+func readTheme(path string) (Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Theme{}, fmt.Errorf("could not read theme file %s: %w", path, err)
+	}
+	var t Theme
+	if err := json.Unmarshal(data, &t); err != nil {
+		return Theme{}, fmt.Errorf("invalid theme file %s: %w", path, err)
+	}
+	if t.Name == "" {
+		t.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	t.Name = strings.ToLower(t.Name)
+	if _, err := t.palette(); err != nil {
+		return Theme{}, fmt.Errorf("invalid theme file %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// FindTheme looks a theme up by name (case-insensitive).
+func FindTheme(themes []Theme, name string) (Theme, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	var names []string
+	for _, t := range themes {
+		if t.Name == name {
+			return t, nil
+		}
+		names = append(names, t.Name)
+	}
+	return Theme{}, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+func (t Theme) palette() ([]color.Color, error) {
+	if len(t.Colors) == 0 {
+		return nil, fmt.Errorf("theme %q has no colors", t.Name)
+	}
+	colors := make([]color.Color, len(t.Colors))
+	for i, s := range t.Colors {
+		c, err := color.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("theme %q: %w", t.Name, err)
+		}
+		colors[i] = c
+	}
+	return colors, nil
+}
+
+// Unit is the piece of text that receives one palette color.
+type Unit int
+
+const (
+	ByChar Unit = iota
+	ByWord
+	ByRow
+)
+
+// ParseUnit converts "char", "word" or "row" into a Unit.
+func ParseUnit(s string) (Unit, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "char", "chars", "character", "characters":
+		return ByChar, nil
+	case "word", "words":
+		return ByWord, nil
+	case "row", "rows":
+		return ByRow, nil
+	}
+	return ByChar, fmt.Errorf("invalid theme unit %q (use char, word or row)", s)
+}
+
+// Palette paints characters, words or glyph rows with the colors of a theme.
+type Palette struct {
+	Colors []color.Color
+	Unit   Unit
+	Random bool  // pick colors at random instead of cycling through them
+	Seed   int64 // seed for Random, so the same seed gives the same art
+}
+
+// NewPalette builds a cycling palette from a theme.
+func NewPalette(t Theme, unit string) (*Palette, error) {
+	u, err := ParseUnit(unit)
+	if err != nil {
+		return nil, err
+	}
+	colors, err := t.palette()
+	if err != nil {
+		return nil, err
+	}
+	return &Palette{Colors: colors, Unit: u}, nil
+}
+
+// Apply paints every glyph cell that has no colour yet. text is the input
+// the canvas was rendered from; whitespace never uses up a palette color.
+func (p *Palette) Apply(c *canvas.Canvas, text string) {
+	// Number every unit in drawing order
+	unitOf := make(map[int]int) // source offset → unit index
+	count := 0
+	switch p.Unit {
+	case ByWord:
+		inWord := false
+		for i := 0; i < len(text); i++ {
+			if isSpace(text[i]) {
+				inWord = false
+				continue
+			}
+			if !inWord {
+				count++
+				inWord = true
+			}
+			unitOf[i] = count - 1
+		}
+	case ByRow:
+		count = canvas.BandHeight
+	default:
+		for i := 0; i < len(text); i++ {
+			if !isSpace(text[i]) {
+				unitOf[i] = count
+				count++
+			}
+		}
+	}
+
+	colors := p.assign(count)
+	for _, row := range c.Rows {
+		for x := range row.Cells {
+			cell := &row.Cells[x]
+			if cell.Src < 0 || cell.Style.FG.Valid() {
+				continue
+			}
+			if p.Unit == ByRow {
+				cell.Style.FG = colors[row.Band]
+			} else if u, ok := unitOf[cell.Src]; ok {
+				cell.Style.FG = colors[u]
+			}
+		}
+	}
+}
+
+// assign returns the color for each of n units
+func (p *Palette) assign(n int) []color.Color {
+	out := make([]color.Color, n)
+	if !p.Random {
+		for i := range out {
+			out[i] = p.Colors[i%len(p.Colors)]
+		}
+		return out
+	}
+
+	rng := rand.New(rand.NewSource(p.Seed))
+	prev := -1
+	for i := range out {
+		k := rng.Intn(len(p.Colors))
+		if k == prev && len(p.Colors) > 1 { // avoid two neighbours sharing a color
+			k = (k + 1 + rng.Intn(len(p.Colors)-1)) % len(p.Colors)
+		}
+		out[i] = p.Colors[k]
+		prev = k
+	}
+	return out
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\t' || b == '\r'
+}
@@ -1,3 +1,7 @@
 module platform.zone01.gr/git/askordal/ascii-art-reverse
 
 go 1.24.1
+
+require platform.zone01.gr/git/askordal/ascii-art-core v0.0.0
+
+replace platform.zone01.gr/git/askordal/ascii-art-core => ../ascii-art-core
@@ -31,32 +31,35 @@ func main() {
 		return
 	}
 
-	outputFile, alignType, inputText, bannerFile, colorTargets, err := utils.ParseArgs(os.Args[1:])
+	opts, err := utils.ParseArgs(os.Args[1:])
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
-	banner, err := utils.LoadBanner(bannerFile)
+	banner, err := utils.LoadBanner(opts.BannerFile)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, utils.UsageMsg)
 		os.Exit(1)
 	}
 
-	asciiArt, err := utils.AsciiArt(inputText, banner, alignType, colorTargets)
+	if opts.OutputFile == "" && opts.Format == "" {
+		asciiArt, err := utils.AsciiArt(opts.Text, banner, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		fmt.Print(asciiArt)
+		return
+	}
+
+	art, err := utils.Render(opts.Text, banner, opts)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "Error:", err)
 		os.Exit(1)
 	}
-
-	if outputFile == "" {
-		fmt.Print(asciiArt)
-	} else {
-		// εδώ strip + write
-		rows := strings.Split(asciiArt, "\n")
-		if err := utils.WriteToFile(rows, outputFile); err != nil {
-			fmt.Fprintln(os.Stderr, "Error writing to file:", err)
-			os.Exit(1)
-		}
+	if err := utils.WriteOutput(art, opts); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing output:", err)
+		os.Exit(1)
 	}
 }
@@ -2,7 +2,15 @@ package utils
 
 import (
 	"fmt"
+	"go/token"
+	"os"
+	"strconv"
 	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+	"platform.zone01.gr/git/askordal/ascii-art-core/export"
+	"platform.zone01.gr/git/askordal/ascii-art-core/paint"
 )
 
 // Help message displayed when usage is incorrect
@@ -24,65 +32,328 @@ Additional color usage:
 
 EX: go run . --color=<color> <substring> [--color=<color> <substring>] [--align=...] [--output=...] "text"
 
+The substring may be a selector; prefixes can be combined, e.g. nth:2:icase:word:go
+  regex:<re>  word:<text>  icase:<text>  nth:<n>:<selector>  range:<from>-<to>  lit:<text>
+Where rules overlap, the longest match wins.
+
+A color may add a background and text attributes (bold, dim, italic, underline, blink, reverse):
+  go run . --color=<fg>/<bg>+bold+underline <substring> "text"
+
+Output formats (inferred from the --output extension when --format is not given; stdout without --output):
+  go run . [--format=<ansi|txt|html|svg|json|png|gif|cast|pdf|markdown|discord|slack|mirc|comment>] [--output=<file>] "text"
+  go run . --format=<discord|slack> "text"   (code blocks split under the message limit; also markdown, mirc for IRC colors)
+  go run . --comment-style=<go|c|python|shell|sql|html|lua> [--comment-box] "text"   (source file headers)
+  go run . --comment-style=go-const [--const-name=<Name>] "text"   (Go string constant)
+  go run . --format=html [--html-theme=<auto|light|dark>] [--background=<color>] [--html-fragment] [--inline-css] "text"
+  go run . --format=pdf [--paper=<a4|letter>] [--poster] --output=banner.pdf "text"   (--poster tiles wide art across pages)
+  go run . --format=svg --svg-pixels --output=logo.svg "text"   (filled cells instead of text)
+  go run . --format=png [--cell=<width>x<height>] [--background=<color>] [--transparent] --output=logo.png "text"
+  go run . --format=gif [--animate=<marquee|typewriter|cycle|blink>] [--delay=<ms>] [--loop=<times, 0 forever>]
+           [--gif-palette=<auto|plan9|websafe>] --output=banner.gif "text"
+  go run . --format=cast [--animate=...] [--delay=<ms>] [--loop=<times>] --output=demo.cast "text"   (asciinema v2)
+
+Gradient usage:
+  go run . --gradient=<color>:<color>[:<color>...] [--gradient-dir=<column|row|char>] "text"
+
+Theme usage (dracula, gruvbox, monokai, nord, rainbow, solarized or a JSON palette in the config dir):
+  go run . --theme=<name> [--theme-by=<char|word|row>] [--seed=<number>] "text"
+
+Markup usage (colors, attributes, banner and alignment inside the text; \{ and \} for literal braces):
+  go run . --markup "{red}Deploy{/} {bold,font=shadow}OK{/}"
+
 Reverse mode (drops color, outputs raw ASCII art in reverse order of lines):
   go run . --reverse=example04.txt`
 
+// Options holds everything parsed from the command line
+type Options struct {
+	OutputFile   string
+	Format       string // exporter name; "" infers it from OutputFile
+	Align        string
+	Text         string
+	BannerFile   string
+	ColorTargets []ColorTarget
+	Gradient     *paint.Gradient
+	Palette      *paint.Palette
+	Markup       bool // parse inline {style}...{/} markup in the text
+	Export       export.Options
+}
+
+// flagPrefixes lists the options accepted before the text argument
+var flagPrefixes = []string{"--output=", "--format=", "--align=", "--color=", "--gradient=", "--gradient-dir=",
+	"--theme=", "--theme-by=", "--seed=", "--cell=", "--background=",
+	"--animate=", "--delay=", "--loop=", "--gif-palette=", "--html-theme=", "--paper=",
+	"--comment-style=", "--const-name="}
+
+// flagSwitches lists the options that take no value
+var flagSwitches = []string{"--markup", "--svg-pixels", "--transparent", "--html-fragment", "--inline-css", "--poster", "--comment-box"}
+
+func isFlag(arg string) bool {
+	for _, s := range flagSwitches {
+		if arg == s {
+			return true
+		}
+	}
+	for _, p := range flagPrefixes {
+		if strings.HasPrefix(arg, p) {
+			return true
+		}
+	}
+	return false
+}
+
 // ParseArgs parses CLI arguments and returns all relevant fields
-func ParseArgs(args []string) (outputFile, alignType, inputText, bannerFile string, colorTargets []ColorTarget, err error) {
-	alignType = "left"
-	bannerFile = "standard"
-	colorTargets = []ColorTarget{}
+func ParseArgs(args []string) (*Options, error) {
+	opts := &Options{
+		Align:        "left",
+		BannerFile:   "standard",
+		ColorTargets: []ColorTarget{},
+	}
+	gradientSpec, gradientDir := "", ""
+	themeName, themeBy, seed := "", "", ""
 
 	// Parse flags
-	for len(args) > 0 && (strings.HasPrefix(args[0], "--output=") ||
-		strings.HasPrefix(args[0], "--align=") ||
-		strings.HasPrefix(args[0], "--color=")) {
-
+	for len(args) > 0 && isFlag(args[0]) {
 		switch {
 		case strings.HasPrefix(args[0], "--output="):
-			outputFile = strings.TrimPrefix(args[0], "--output=")
+			opts.OutputFile = strings.TrimPrefix(args[0], "--output=")
+			args = args[1:]
+
+		case strings.HasPrefix(args[0], "--format="):
+			opts.Format = strings.TrimPrefix(args[0], "--format=")
 			args = args[1:]
+			if _, err := export.Lookup(opts.Format); err != nil {
+				return nil, fmt.Errorf("%v\n\n%s", err, UsageMsg)
+			}
 
 		case strings.HasPrefix(args[0], "--align="):
-			alignType = strings.TrimPrefix(args[0], "--align=")
+			opts.Align = strings.TrimPrefix(args[0], "--align=")
 			args = args[1:]
 
 		case strings.HasPrefix(args[0], "--color="):
 			colorCode := strings.TrimPrefix(args[0], "--color=")
 			args = args[1:]
+			fg, bg, _, err := canvas.SplitStyle(colorCode)
+			if err != nil {
+				return nil, fmt.Errorf("%v\n\n%s", err, UsageMsg)
+			}
+			for _, code := range []string{fg, bg} {
+				if _, err := color.Parse(code); code != "" && err != nil {
+					return nil, fmt.Errorf("%v\n\n%s", err, UsageMsg)
+				}
+			}
 
 			substring := ""
 			if len(args) > 1 && !strings.HasPrefix(args[0], "--") {
 				substring = args[0]
 				args = args[1:]
+				if _, err := paint.ParseSelector(substring); err != nil {
+					return nil, fmt.Errorf("%v\n\n%s", err, UsageMsg)
+				}
+			}
+			opts.ColorTargets = append(opts.ColorTargets, ColorTarget{ColorCode: colorCode, Substring: substring})
+
+		case strings.HasPrefix(args[0], "--gradient="):
+			gradientSpec = strings.TrimPrefix(args[0], "--gradient=")
+			args = args[1:]
+
+		case strings.HasPrefix(args[0], "--gradient-dir="):
+			gradientDir = strings.TrimPrefix(args[0], "--gradient-dir=")
+			args = args[1:]
+
+		case strings.HasPrefix(args[0], "--theme="):
+			themeName = strings.TrimPrefix(args[0], "--theme=")
+			args = args[1:]
+
+		case strings.HasPrefix(args[0], "--theme-by="):
+			themeBy = strings.TrimPrefix(args[0], "--theme-by=")
+			args = args[1:]
+
+		case args[0] == "--markup":
+			opts.Markup = true
+			args = args[1:]
+
+		case args[0] == "--svg-pixels":
+			opts.Export.SVGPixels = true
+			args = args[1:]
+
+		case args[0] == "--transparent":
+			opts.Export.Transparent = true
+			args = args[1:]
+
+		case strings.HasPrefix(args[0], "--cell="):
+			spec := strings.TrimPrefix(args[0], "--cell=")
+			args = args[1:]
+			w, h, ok := strings.Cut(spec, "x")
+			cw, errW := strconv.Atoi(w)
+			ch, errH := strconv.Atoi(h)
+			if !ok || errW != nil || errH != nil || cw < 1 || ch < 1 {
+				return nil, fmt.Errorf("invalid cell size %q (want <width>x<height> in pixels)\n\n%s", spec, UsageMsg)
+			}
+			opts.Export.CellW, opts.Export.CellH = cw, ch
+
+		case strings.HasPrefix(args[0], "--background="):
+			bg, err := color.Parse(strings.TrimPrefix(args[0], "--background="))
+			args = args[1:]
+			if err != nil {
+				return nil, fmt.Errorf("%v\n\n%s", err, UsageMsg)
+			}
+			opts.Export.Background = bg
+
+		case strings.HasPrefix(args[0], "--seed="):
+			seed = strings.TrimPrefix(args[0], "--seed=")
+			args = args[1:]
+
+		case strings.HasPrefix(args[0], "--html-theme="):
+			opts.Export.HTMLTheme = strings.TrimPrefix(args[0], "--html-theme=")
+			args = args[1:]
+			if err := export.CheckHTMLTheme(opts.Export.HTMLTheme); err != nil {
+				return nil, fmt.Errorf("%v\n\n%s", err, UsageMsg)
+			}
+
+		case strings.HasPrefix(args[0], "--comment-style="):
+			opts.Export.CommentStyle = strings.TrimPrefix(args[0], "--comment-style=")
+			args = args[1:]
+			if err := export.CheckCommentStyle(opts.Export.CommentStyle); err != nil {
+				return nil, fmt.Errorf("%v\n\n%s", err, UsageMsg)
+			}
+
+		case args[0] == "--comment-box":
+			opts.Export.CommentBox = true
+			args = args[1:]
+
+		case strings.HasPrefix(args[0], "--const-name="):
+			opts.Export.ConstName = strings.TrimPrefix(args[0], "--const-name=")
+			args = args[1:]
+			if !token.IsIdentifier(opts.Export.ConstName) {
+				return nil, fmt.Errorf("invalid constant name %q\n\n%s", opts.Export.ConstName, UsageMsg)
+			}
+
+		case strings.HasPrefix(args[0], "--paper="):
+			opts.Export.Paper = strings.TrimPrefix(args[0], "--paper=")
+			args = args[1:]
+			if err := export.CheckPaper(opts.Export.Paper); err != nil {
+				return nil, fmt.Errorf("%v\n\n%s", err, UsageMsg)
+			}
+
+		case args[0] == "--poster":
+			opts.Export.Poster = true
+			args = args[1:]
+
+		case args[0] == "--html-fragment":
+			opts.Export.HTMLFragment = true
+			args = args[1:]
+
+		case args[0] == "--inline-css":
+			opts.Export.InlineCSS = true
+			args = args[1:]
+
+		case strings.HasPrefix(args[0], "--animate="):
+			opts.Export.Animation = strings.TrimPrefix(args[0], "--animate=")
+			args = args[1:]
+
+		case strings.HasPrefix(args[0], "--gif-palette="):
+			opts.Export.GIFPalette = strings.TrimPrefix(args[0], "--gif-palette=")
+			args = args[1:]
+
+		case strings.HasPrefix(args[0], "--delay="), strings.HasPrefix(args[0], "--loop="):
+			name, value, _ := strings.Cut(args[0], "=")
+			args = args[1:]
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s value %q (want a whole number)\n\n%s", name, value, UsageMsg)
+			}
+			if name == "--delay" {
+				opts.Export.Delay = n
+			} else {
+				opts.Export.Loops = n
 			}
-			colorTargets = append(colorTargets, ColorTarget{ColorCode: colorCode, Substring: substring})
 
 		default:
-			return "", "", "", "", nil, fmt.Errorf("unrecognized option: %q\n\n%s", args[0], UsageMsg)
+			return nil, fmt.Errorf("unrecognized option: %q\n\n%s", args[0], UsageMsg)
 		}
 	}
 
+	// A comment style picks the comment format unless another was asked for
+	if opts.Export.CommentStyle != "" && opts.Format == "" {
+		opts.Format = "comment"
+	}
+
+	if err := export.CheckAnimation(opts.Export); err != nil {
+		return nil, fmt.Errorf("%v\n\n%s", err, UsageMsg)
+	}
+
 	// Validate alignment
 	validAligns := map[string]bool{"left": true, "right": true, "center": true, "justify": true}
-	if !validAligns[alignType] {
-		return "", "", "", "", nil, fmt.Errorf("invalid alignment option: %q\n\n%s", alignType, UsageMsg)
+	if !validAligns[opts.Align] {
+		return nil, fmt.Errorf("invalid alignment option: %q\n\n%s", opts.Align, UsageMsg)
+	}
+
+	// Validate gradient
+	if gradientSpec != "" {
+		g, err := paint.ParseGradient(gradientSpec, gradientDir)
+		if err != nil {
+			return nil, fmt.Errorf("%v\n\n%s", err, UsageMsg)
+		}
+		opts.Gradient = g
+	} else if gradientDir != "" {
+		return nil, fmt.Errorf("--gradient-dir requires --gradient\n\n%s", UsageMsg)
+	}
+
+	// Validate theme
+	if themeName != "" {
+		if opts.Gradient != nil {
+			return nil, fmt.Errorf("--theme and --gradient cannot be combined\n\n%s", UsageMsg)
+		}
+		p, err := loadPalette(themeName, themeBy, seed)
+		if err != nil {
+			return nil, fmt.Errorf("%v\n\n%s", err, UsageMsg)
+		}
+		opts.Palette = p
+	} else if themeBy != "" || seed != "" {
+		return nil, fmt.Errorf("--theme-by and --seed require --theme\n\n%s", UsageMsg)
 	}
 
 	// Require at least one argument for text
 	if len(args) < 1 {
-		return "", "", "", "", nil, fmt.Errorf("missing required text argument\n\n%s", UsageMsg)
+		return nil, fmt.Errorf("missing required text argument\n\n%s", UsageMsg)
 	}
 
-	text := strings.ReplaceAll(args[0], "\\n", "\n")
+	opts.Text = strings.ReplaceAll(args[0], "\\n", "\n")
 	if len(args) >= 2 {
-		bannerFile = args[1]
-		if !strings.HasSuffix(bannerFile, ".txt") {
-			bannerFile += ".txt"
-		}
-	} else {
-		bannerFile += ".txt"
+		opts.BannerFile = args[1]
+	}
+	if !strings.HasSuffix(opts.BannerFile, ".txt") {
+		opts.BannerFile += ".txt"
 	}
 
-	return outputFile, alignType, text, bannerFile, colorTargets, nil
+	return opts, nil
+}
+
+// loadPalette finds a built-in or user theme and prepares it for painting;
+// a seed switches from cycling colors to a repeatable random pick
+func loadPalette(name, unit, seed string) (*paint.Palette, error) {
+	themes, skipped, err := paint.LoadThemes(paint.ThemeDir())
+	if err != nil {
+		return nil, err
+	}
+	for _, problem := range skipped {
+		fmt.Fprintln(os.Stderr, "Warning: skipped", problem)
+	}
+	theme, err := paint.FindTheme(themes, name)
+	if err != nil {
+		return nil, err
+	}
+	p, err := paint.NewPalette(theme, unit)
+	if err != nil {
+		return nil, err
+	}
+	if seed != "" {
+		n, err := strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seed %q: must be a whole number", seed)
+		}
+		p.Random = true
+		p.Seed = n
+	}
+	return p, nil
 }
@@ -6,6 +6,8 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
 )
 
 // getTerminalWidth retrieves the current terminal width using stty command
@@ -19,27 +21,40 @@ func getTerminalWidth() int {
 }
 
 // stretching only the spaces between words to fill exactly 'width' columns.
-func justifyAscii(line string, banner map[rune][]string, width int, colorTargets []ColorTarget) ([]string, error) {
-	words := strings.Fields(line)
-	n := len(words)
-	if n == 0 {
-		return make([]string, blockLines), nil
+// offset is the position of line inside the full input text.
+func justifyAscii(line string, offset int, fonts []map[rune][]string, width int, styles []canvas.Style) ([][]canvas.Cell, error) {
+	if len(strings.Fields(line)) == 0 {
+		return make([][]canvas.Cell, blockLines), nil
+	}
+
+	// Render the whole line once so colour rules see the same text as usual
+	rows, err := buildAsciiRowsWithColor(line, offset, fonts, styles)
+	if err != nil {
+		return nil, fmt.Errorf("error building ASCII for line %q: %w", line, err)
 	}
 
-	// Render each word with color support
-	wordRows := make([][]string, n)
-	for i, w := range words {
-		rows, err := buildAsciiRowsWithColor(w, banner, colorTargets)
-		if err != nil {
-			return nil, fmt.Errorf("error building ASCII for word %q: %w", w, err)
+	// Locate the columns each word occupies (all rows share the same layout)
+	type span struct{ from, to int }
+	var words []span
+	inWord := false
+	for x, cell := range rows[0] {
+		isSpace := line[cell.Src-offset] == ' '
+		switch {
+		case !isSpace && !inWord:
+			words = append(words, span{x, x + 1})
+			inWord = true
+		case !isSpace:
+			words[len(words)-1].to = x + 1
+		default:
+			inWord = false
 		}
-		wordRows[i] = rows
 	}
+	n := len(words)
 
-	// Measure visible length of each word (excluding ANSI codes)
+	// Measure visible length of each word
 	totalWordLen := 0
-	for _, wr := range wordRows {
-		totalWordLen += visibleLength(wr[0]) // all rows have same length
+	for _, w := range words {
+		totalWordLen += w.to - w.from
 	}
 
 	// Determine spacing
@@ -47,7 +62,7 @@ func justifyAscii(line string, banner map[rune][]string, width int, colorTargets
 	extra := width - totalWordLen
 	if slots <= 0 || extra <= 0 {
 		// fallback to left-align
-		return buildAsciiRowsWithColor(line, banner, colorTargets)
+		return rows, nil
 	}
 
 	base := extra / slots
@@ -61,12 +76,12 @@ func justifyAscii(line string, banner map[rune][]string, width int, colorTargets
 	}
 
 	// Stitch words and gaps identically across rows
-	result := make([]string, blockLines)
+	result := make([][]canvas.Cell, blockLines)
 	for row := 0; row < blockLines; row++ {
-		for wi, wr := range wordRows {
-			result[row] += wr[row]
+		for wi, w := range words {
+			result[row] = append(result[row], rows[row][w.from:w.to]...)
 			if wi < slots {
-				result[row] += strings.Repeat(" ", gaps[wi])
+				result[row] = append(result[row], canvas.Pad(gaps[wi])...)
 			}
 		}
 	}
@@ -75,13 +90,12 @@ func justifyAscii(line string, banner map[rune][]string, width int, colorTargets
 }
 
 // alignRows adjusts the position of ASCII art based on alignment type
-func alignRows(rows []string, align string, width int) ([]string, error) {
+func alignRows(rows [][]canvas.Cell, align string, width int) ([][]canvas.Cell, error) {
 	if align == "left" {
 		return rows, nil
 	}
 
-	// Use visible length (excluding ANSI escape codes)
-	lineLen := visibleLength(rows[0])
+	lineLen := len(rows[0])
 	if width < lineLen {
 		return nil, fmt.Errorf("terminal width too small for alignment")
 	}
@@ -99,7 +113,7 @@ func alignRows(rows []string, align string, width int) ([]string, error) {
 
 	// Prepend spaces to each row
 	for i := range rows {
-		rows[i] = strings.Repeat(" ", pad) + rows[i]
+		rows[i] = append(canvas.Pad(pad), rows[i]...)
 	}
 	return rows, nil
 }
@@ -5,50 +5,61 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/export"
 )
 
-// StripANSI removes ANSI escape sequences.
-func StripANSI(s string) string {
-	var b strings.Builder
-	for i := 0; i < len(s); {
-		if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '[' {
-			i += 2
-			for i < len(s) && s[i] != 'm' {
-				i++
-			}
-			i++ // skip 'm'
-		} else {
-			b.WriteByte(s[i])
-			i++
-		}
-	}
-	return b.String()
+// pickExporter chooses the output format: --format if given, else the
+// extension of --output, else plain text
+func pickExporter(opts *Options) (*export.Exporter, error) {
+	if opts.Format != "" {
+		return export.Lookup(opts.Format)
+	}
+	if e, ok := export.ForPath(opts.OutputFile); ok {
+		return e, nil
+	}
+	return export.Lookup("txt")
 }
 
-// WriteToFile strips ANSI codes and writes lines to a file (adds .txt if needed).
-func WriteToFile(rows []string, path string) error {
-	if filepath.Ext(path) == "" {
-		path += ".txt"
+// WriteOutput exports the canvas to --output (adding the format's extension
+// if the name has none), or to standard output when no file is given.
+func WriteOutput(c *canvas.Canvas, opts *Options) error {
+	e, err := pickExporter(opts)
+	if err != nil {
+		return err
 	}
-	hasANSI := false
-	for _, r := range rows {
-		if strings.IndexByte(r, '\x1b') >= 0 {
-			hasANSI = true
-			break
-		}
+	exportOpts := opts.Export
+	exportOpts.TrueColor = trueColorSupported()
+	exportOpts.Warn = func(msg string) {
+		fmt.Fprintf(os.Stderr, "\x1b[33mwarning: %s\x1b[0m\n", msg)
 	}
-	if hasANSI {
-		fmt.Fprintf(os.Stderr, "\x1b[31mwarning: stripping ANSI codes to %s\x1b[0m\n", path)
+	exportOpts.Meta = export.Meta{
+		Text:   opts.Text,
+		Markup: opts.Markup,
+		Banner: strings.TrimSuffix(opts.BannerFile, ".txt"),
+		Align:  opts.Align,
+		Width:  getTerminalWidth(),
+	}
+
+	if opts.OutputFile == "" {
+		return e.Write(os.Stdout, c, exportOpts)
+	}
+
+	path := opts.OutputFile
+	if filepath.Ext(path) == "" {
+		path += "." + e.Ext
+	}
+	if e.Name == "txt" && c.Styled() {
+		fmt.Fprintf(os.Stderr, "\x1b[31mwarning: stripping ANSI codes to %s (use --format=ansi to keep colors)\x1b[0m\n", path)
 	}
 	f, err := os.Create(path)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	for _, r := range rows {
-		if _, err := f.WriteString(StripANSI(r) + "\n"); err != nil {
-			return err
-		}
+	if err := e.Write(f, c, exportOpts); err != nil {
+		f.Close()
+		return err
 	}
-	return nil
+	return f.Close()
 }
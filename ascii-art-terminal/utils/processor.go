@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/markup"
 )
 
 const (
@@ -54,20 +57,50 @@ func LoadBanner(fileName string) (map[rune][]string, error) {
 	return banner, nil
 }
 
-// AsciiArt renders input text to ASCII art respecting alignment and colours
-func AsciiArt(input string, banner map[rune][]string, align string, colorTargets []ColorTarget) (string, error) {
+// Render draws input text onto a canvas respecting alignment, colours and gradient
+func Render(input string, banner map[rune][]string, opts *Options) (*canvas.Canvas, error) {
 	width := getTerminalWidth()
-	var out strings.Builder
+	align := opts.Align
+	out := &canvas.Canvas{}
+
+	// Strip inline markup first; it may pick colours, banners and alignment
+	var marks *markup.Result
+	if opts.Markup {
+		var err error
+		if marks, err = markup.Parse(input); err != nil {
+			return nil, err
+		}
+		input = marks.Text
+	}
 
 	if input == "" { // absolutely empty: no output
-		return "", nil
+		return out, nil
+	}
+
+	colorTargets := opts.ColorTargets
+	if opts.Gradient != nil || opts.Palette != nil {
+		colorTargets = withoutDefaultColor(colorTargets)
+	}
+	styles, err := textStyles(input, colorTargets)
+	if err != nil {
+		return nil, err
+	}
+	fonts, err := charFonts(input, banner, marks)
+	if err != nil {
+		return nil, err
+	}
+	if marks != nil {
+		markup.Merge(styles, marks.Styles)
 	}
 
 	// keep the trailing \n tokens so we know exactly how many blank lines the user asked for
 	chunks := strings.SplitAfter(input, "\n")
-	for _, chunk := range chunks {
+	offset := 0
+	for li, chunk := range chunks {
+		start := offset
+		offset += len(chunk)
 		if chunk == "\n" { // explicit blank line → single raw newline out
-			out.WriteString("\n")
+			out.AddBlank()
 			continue
 		}
 
@@ -77,51 +110,110 @@ func AsciiArt(input string, banner map[rune][]string, align string, colorTargets
 			continue
 		}
 
+		// markup may align single lines differently
+		lineAlign := align
+		if marks != nil && marks.Aligns[li] != "" {
+			lineAlign = marks.Aligns[li]
+		}
+
 		// JUSTIFY handling
-		if align == "justify" && len(strings.Fields(line)) > 1 {
-			rows, err := justifyAscii(line, banner, width, colorTargets)
+		if lineAlign == "justify" && len(strings.Fields(line)) > 1 {
+			rows, err := justifyAscii(line, start, fonts, width, styles)
 			if err != nil {
-				return "", err
-			}
-			for _, r := range rows {
-				out.WriteString(r + "\n")
+				return nil, err
 			}
+			out.AddBand(rows)
 			continue
-		} else if align == "justify" {
+		} else if lineAlign == "justify" {
 			fmt.Fprintln(os.Stderr, "\033[33mwarning: cannot justify line with one word, using left align\033[0m")
-			align = "left"
+			if align == "justify" {
+				align = "left"
+			}
+			lineAlign = "left"
 		}
 
 		// Normal rendering path
-		rows, err := buildAsciiRowsWithColor(line, banner, colorTargets)
+		rows, err := buildAsciiRowsWithColor(line, start, fonts, styles)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		rows, err = alignRows(rows, align, width)
+		rows, err = alignRows(rows, lineAlign, width)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		for _, r := range rows {
-			out.WriteString(r + "\n")
+		out.AddBand(rows)
+	}
+
+	if opts.Gradient != nil {
+		opts.Gradient.Apply(out)
+	}
+	if opts.Palette != nil {
+		opts.Palette.Apply(out, input)
+	}
+	return out, nil
+}
+
+// charFonts returns the banner to draw each character of input with:
+// the default one, unless markup switched fonts
+func charFonts(input string, banner map[rune][]string, marks *markup.Result) ([]map[rune][]string, error) {
+	fonts := make([]map[rune][]string, len(input))
+	loaded := map[string]map[rune][]string{"": banner}
+	for i := range fonts {
+		name := ""
+		if marks != nil {
+			name = marks.Fonts[i]
 		}
+		if _, ok := loaded[name]; !ok {
+			b, err := LoadBanner(name + ".txt")
+			if err != nil {
+				return nil, fmt.Errorf("markup font %q: %w", name, err)
+			}
+			loaded[name] = b
+		}
+		fonts[i] = loaded[name]
 	}
+	return fonts, nil
+}
 
-	return out.String(), nil
+// AsciiArt renders input text to ASCII art respecting alignment and colours
+func AsciiArt(input string, banner map[rune][]string, opts *Options) (string, error) {
+	c, err := Render(input, banner, opts)
+	if err != nil {
+		return "", err
+	}
+	return c.ANSI(trueColorSupported()), nil
+}
+
+// withoutDefaultColor drops whole-text colours, which a gradient or theme replaces
+func withoutDefaultColor(targets []ColorTarget) []ColorTarget {
+	var kept []ColorTarget
+	for _, t := range targets {
+		if t.Substring == "" {
+			fmt.Fprintln(os.Stderr, "\033[33mwarning: --gradient and --theme replace the default color\033[0m")
+			continue
+		}
+		kept = append(kept, t)
+	}
+	return kept
 }
 
-// buildAsciiRows converts a single line to ASCII art rows
-func buildAsciiRows(line string, banner map[rune][]string) ([]string, error) {
-	rows := make([]string, blockLines) // prepare rows
-	for _, ch := range line {
+// buildAsciiRows converts a single line to ASCII art rows.
+// offset is the position of line inside the full input text, and fonts
+// holds the banner of every character of that text.
+func buildAsciiRows(line string, offset int, fonts []map[rune][]string) ([][]canvas.Cell, error) {
+	rows := make([][]canvas.Cell, blockLines) // prepare rows
+	for idx, ch := range line {
 		if ch < spaceAscii || ch > tildeAscii {
 			return nil, fmt.Errorf("unsupported char: %q", ch) // validate char
 		}
-		ascii, ok := banner[ch]
+		ascii, ok := fonts[offset+idx][ch]
 		if !ok || len(ascii) != blockLines {
 			return nil, fmt.Errorf("char %q missing banner data", ch)
 		}
 		for i := 0; i < blockLines; i++ {
-			rows[i] += ascii[i] // build each row
+			for j := 0; j < len(ascii[i]); j++ { // build each row
+				rows[i] = append(rows[i], canvas.Cell{Ch: ascii[i][j], Src: offset + idx})
+			}
 		}
 	}
 	return rows, nil
@@ -1,8 +1,7 @@
 let globalColorValue = '#ff0000';
-let targetColorValue = '#00ffff';
 let backgroundColorValue = '#f8f9f9';
 
-let globalPicker, targetPicker, backgroundPicker;
+let globalPicker, backgroundPicker;
 
 function initColors() {
   const pre = document.getElementById('asciiOutput');
@@ -14,13 +13,6 @@ function initColors() {
     borderColor: "#ccc"
   });
 
-  targetPicker = new iro.ColorPicker("#targetColorWheel", {
-    width: 100,
-    color: targetColorValue,
-    borderWidth: 1,
-    borderColor: "#ccc"
-  });
-
   backgroundPicker = new iro.ColorPicker("#backgroundColorWheel", {
     width: 100,
     color: backgroundColorValue,
@@ -34,16 +26,11 @@ function initColors() {
     scheduleGenerate();
   });
 
-  targetPicker.on('color:change', color => {
-    targetColorValue = color.hexString;
-    updateRadioSelection('targetColor', targetColorValue);
-    scheduleGenerate();
-  });
-
   backgroundPicker.on('color:change', color => {
     backgroundColorValue = color.hexString;
     updateRadioSelection('backgroundPreset', backgroundColorValue);
     pre.style.backgroundColor = backgroundColorValue;
+    pre.style.setProperty('--ascii-bg', backgroundColorValue);
   });
 
   document.querySelectorAll('input[name="color"]').forEach(r => {
@@ -54,19 +41,12 @@ function initColors() {
     });
   });
 
-  document.querySelectorAll('input[name="targetColor"]').forEach(r => {
-    r.addEventListener('change', e => {
-      targetColorValue = e.target.value;
-      targetPicker.color.hexString = targetColorValue;
-      scheduleGenerate();
-    });
-  });
-
   document.querySelectorAll('input[name="backgroundPreset"]').forEach(r => {
     r.addEventListener('change', e => {
       backgroundColorValue = e.target.value;
       backgroundPicker.color.hexString = backgroundColorValue;
       pre.style.backgroundColor = backgroundColorValue;
+      pre.style.setProperty('--ascii-bg', backgroundColorValue);
     });
   });
 }
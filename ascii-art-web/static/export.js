@@ -23,22 +23,41 @@ function initExport() {
     const format = document.getElementById('format').value;
     const filename = document.getElementById('filename').value.trim() || 'ascii-art-web-export';
 
-    const formData = new FormData();
-    formData.append("asciiText", output.textContent);
+    // The server renders the art again from the sidebar settings
+    const formData = renderFormData();
     formData.append("format", format);
     formData.append("filename", filename);
+    if (document.getElementById('svgPixels').checked) formData.append("svgPixels", "on");
+    // The preview background chosen in the sidebar carries over to images and HTML
+    formData.append("background", backgroundColorValue);
+    if (document.getElementById('pngTransparent').checked) formData.append("transparent", "on");
+    formData.append("animation", document.getElementById('animation').value);
+    formData.append("delay", document.getElementById('gifDelay').value);
+    formData.append("loops", document.getElementById('gifLoops').value);
+    formData.append("gifPalette", document.getElementById('gifPalette').value);
+    formData.append("paper", document.getElementById('paper').value);
+    if (document.getElementById('poster').checked) formData.append("poster", "on");
+    formData.append("htmlTheme", document.getElementById('htmlTheme').value);
+    if (document.getElementById('htmlFragment').checked) formData.append("htmlFragment", "on");
+    if (document.getElementById('inlineCss').checked) formData.append("inlineCss", "on");
 
     fetch("/export", { method: "POST", body: formData })
       .then(res => {
         if (!res.ok) throw new Error("Export failed.");
-        return res.blob();
+        // The server names the file with the format's extension
+        const match = /filename="([^"]+)"/.exec(res.headers.get("Content-Disposition") || "");
+        const name = match ? match[1] : `${filename}.${format}`;
+        const warning = res.headers.get("X-Export-Warning");
+        return res.blob().then(blob => ({ blob, name, warning }));
       })
-      .then(blob => {
+      .then(({ blob, name, warning }) => {
         const a = document.createElement("a");
         a.href = URL.createObjectURL(blob);
-        a.download = `${filename}.${format}`;
+        a.download = name;
         a.click();
         URL.revokeObjectURL(a.href);
+        // Chat formats report art too wide for the platform or split messages
+        if (warning) alert("Note: " + warning);
       })
       .catch(err => {
         alert("Export error: " + err.message);
@@ -1,6 +1,9 @@
 let debounceTimer;
+let resizeTimer;
 const DEBOUNCE_MS = 500;
+const MAX_COLUMNS = 1000;
 let justCleared = false;
+let lastColumns = 0;
 
 function initGenerate() {
   const form = document.getElementById('asciiForm');
@@ -20,23 +23,42 @@ function initGenerate() {
     form.reset();
     counter.textContent = '0/1000000';
     pre.textContent = '';
-    pre.className = 'align-left';
     err.hidden = true;
     err.innerHTML = '';
     justCleared = true;
 
     globalColorValue = '#ff0000';
-    targetColorValue = '#00ffff';
     backgroundColorValue = '#f8f9f9';
 
     globalPicker.color.hexString = globalColorValue;
-    targetPicker.color.hexString = targetColorValue;
     backgroundPicker.color.hexString = backgroundColorValue;
     pre.style.backgroundColor = backgroundColorValue;
+    pre.style.setProperty('--ascii-bg', backgroundColorValue);
 
     updateRadioSelection('color', globalColorValue);
-    updateRadioSelection('targetColor', targetColorValue);
     updateRadioSelection('backgroundPreset', backgroundColorValue);
+    resetGradient();
+    resetTargetRules();
+    showThemePreview();
+  });
+
+  // Align and wrap again when the pane fits a different number of columns
+  lastColumns = previewColumns();
+  window.addEventListener('resize', () => {
+    clearTimeout(resizeTimer);
+    resizeTimer = setTimeout(() => {
+      const columns = previewColumns();
+      if (columns === lastColumns) return;
+      lastColumns = columns;
+      if (form.inputText.value.trim()) doGenerate();
+    }, DEBOUNCE_MS);
+  });
+
+  // An opened file replaces the preview rather than changing the settings
+  const ansFile = document.getElementById('ansFile');
+  ['input', 'change'].forEach(type => ansFile.addEventListener(type, e => e.stopPropagation()));
+  ansFile.addEventListener('change', () => {
+    if (ansFile.files.length) openAnsFile(ansFile.files[0]);
   });
 
   form.addEventListener('input', scheduleGenerate);
@@ -66,23 +88,10 @@ async function doGenerate() {
 
   err.hidden = true;
   err.innerHTML = '';
-  pre.className = '';
   pre.innerHTML = '';
 
-  const fd = new FormData();
-  fd.append('inputText', text);
-  fd.append('banner', form.banner.value);
-  fd.append('align', form.align.value);
-  fd.append('color', globalColorValue);
-
-  const targets = form.colorTarget.value.split(',').map(s => s.trim()).filter(Boolean);
-  targets.forEach(t => {
-    fd.append('colorTarget', t);
-    fd.append('targetColor', targetColorValue);
-  });
-
   try {
-    const res = await fetch('/ascii-art', { method: 'POST', body: fd });
+    const res = await fetch('/ascii-art', { method: 'POST', body: renderFormData() });
     const html = await res.text();
 
     if (!res.ok) {
@@ -95,9 +104,84 @@ async function doGenerate() {
     wrapper.className = 'inner';
     wrapper.innerHTML = html;
     pre.appendChild(wrapper);
-    pre.classList.add('align-' + form.align.value);
   } catch (e) {
     err.textContent = 'Network error: ' + e.message;
     err.hidden = false;
   }
 }
+
+// openAnsFile shows an uploaded .ans file, converted by the server
+async function openAnsFile(file) {
+  const pre = document.getElementById('asciiOutput');
+  const err = document.getElementById('error');
+  const fd = new FormData();
+  fd.append('file', file);
+
+  err.hidden = true;
+  err.innerHTML = '';
+  try {
+    const res = await fetch('/ansi', { method: 'POST', body: fd });
+    const html = await res.text();
+    if (!res.ok) {
+      err.innerHTML = html || '❌ Something went wrong.';
+      err.hidden = false;
+      return;
+    }
+    const wrapper = document.createElement('div');
+    wrapper.className = 'inner';
+    wrapper.innerHTML = html;
+    pre.innerHTML = '';
+    pre.appendChild(wrapper);
+  } catch (e) {
+    err.textContent = 'Network error: ' + e.message;
+    err.hidden = false;
+  }
+}
+
+// renderFormData collects the render settings of the sidebar; the preview
+// and the export send the same fields, so downloads match what is shown
+function renderFormData() {
+  const form = document.getElementById('asciiForm');
+  const fd = new FormData();
+  fd.append('inputText', form.inputText.value.trim());
+  fd.append('banner', form.banner.value);
+  fd.append('align', form.align.value);
+  const columns = previewColumns();
+  if (columns) {
+    fd.append('width', columns);
+    fd.append('wrap', 'on');
+  }
+  fd.append('color', globalColorValue);
+  if (form.markup.checked) fd.append('markup', 'on');
+
+  const gradient = gradientSpec();
+  if (gradient) {
+    fd.append('gradient', gradient);
+    fd.append('gradientDirection', form.gradientDirection.value);
+  } else if (form.theme.value) {
+    fd.append('theme', form.theme.value);
+    fd.append('themeBy', form.themeBy.value);
+    fd.append('seed', form.seed.value);
+  }
+
+  appendTargetRules(fd);
+  return fd;
+}
+
+// previewColumns counts the monospace characters that fit across the
+// preview pane, so the server aligns and wraps to what is visible; 0 when
+// the pane can't be measured
+function previewColumns() {
+  const pre = document.getElementById('asciiOutput');
+  const probe = document.createElement('span');
+  probe.textContent = 'M'.repeat(100);
+  probe.style.cssText = 'position: absolute; visibility: hidden; white-space: pre;';
+  pre.appendChild(probe);
+  const charWidth = probe.getBoundingClientRect().width / 100;
+  probe.remove();
+
+  const style = getComputedStyle(pre);
+  const room = pre.clientWidth - parseFloat(style.paddingLeft) - parseFloat(style.paddingRight);
+  if (!charWidth || room <= 0) return 0;
+  return Math.max(1, Math.min(MAX_COLUMNS, Math.floor(room / charWidth)));
+}
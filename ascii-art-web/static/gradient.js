@@ -0,0 +1,40 @@
+const DEFAULT_GRADIENT_STOPS = ['#ff0000', '#0000ff'];
+const MAX_GRADIENT_STOPS = 6;
+
+function initGradient() {
+  document.getElementById('addGradientStop').addEventListener('click', () => {
+    const stops = document.querySelectorAll('#gradientStops input');
+    if (stops.length >= MAX_GRADIENT_STOPS) return;
+    addGradientStop(stops[stops.length - 1].value);
+    scheduleGenerate();
+  });
+
+  document.getElementById('removeGradientStop').addEventListener('click', () => {
+    const stops = document.querySelectorAll('#gradientStops input');
+    if (stops.length <= 2) return;
+    stops[stops.length - 1].remove();
+    scheduleGenerate();
+  });
+
+  resetGradient();
+}
+
+function addGradientStop(value) {
+  const input = document.createElement('input');
+  input.type = 'color';
+  input.value = value;
+  document.getElementById('gradientStops').appendChild(input);
+}
+
+function resetGradient() {
+  document.getElementById('gradientStops').innerHTML = '';
+  DEFAULT_GRADIENT_STOPS.forEach(addGradientStop);
+}
+
+// gradientSpec returns the stops as "#rrggbb:#rrggbb", or '' when disabled
+function gradientSpec() {
+  if (!document.getElementById('gradientEnabled').checked) return '';
+  return Array.from(document.querySelectorAll('#gradientStops input'))
+    .map(i => i.value)
+    .join(':');
+}
@@ -1,5 +1,8 @@
 window.addEventListener("DOMContentLoaded", () => {
   initColors();
+  initGradient();
+  initTargets();
+  initThemes();
   initDropdown();
   initGenerate();
   initExport();
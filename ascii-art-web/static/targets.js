@@ -0,0 +1,145 @@
+const MAX_TARGET_RULES = 20;
+const TARGET_ATTRS = [
+  { value: 'bold', label: '<b>B</b>' },
+  { value: 'dim', label: '<span style="opacity:0.6">D</span>' },
+  { value: 'italic', label: '<i>I</i>' },
+  { value: 'underline', label: '<u>U</u>' },
+  { value: 'blink', label: '<span class="ansi-blink">✦</span>' },
+  { value: 'reverse', label: '◐' },
+];
+
+// targetRules is the page state of the target list, in priority order:
+// where two rules match the same number of characters, the later one wins
+let targetRules = [];
+
+function newTargetRule() {
+  return { selector: '', color: '#00ffff', bgEnabled: false, bg: '#ffff00', attrs: [] };
+}
+
+function initTargets() {
+  document.getElementById('addTargetRule').addEventListener('click', () => {
+    if (targetRules.length >= MAX_TARGET_RULES) return;
+    targetRules.push(newTargetRule());
+    renderTargetRules();
+    saveTargetRules();
+  });
+
+  // Restore the rules kept in the history entry, e.g. after a reload
+  const saved = history.state && history.state.targetRules;
+  if (Array.isArray(saved) && saved.length) {
+    targetRules = saved.map(r => Object.assign(newTargetRule(), r));
+    renderTargetRules();
+  } else {
+    resetTargetRules();
+  }
+}
+
+function resetTargetRules() {
+  targetRules = [newTargetRule()];
+  renderTargetRules();
+  saveTargetRules();
+}
+
+// saveTargetRules keeps the rules in the history entry of the page
+function saveTargetRules() {
+  history.replaceState(Object.assign({}, history.state, { targetRules }), '');
+}
+
+// changeTargetRules applies an edit that the form doesn't see as input
+function changeTargetRules() {
+  renderTargetRules();
+  saveTargetRules();
+  scheduleGenerate();
+}
+
+function renderTargetRules() {
+  const list = document.getElementById('targetRules');
+  list.innerHTML = '';
+  targetRules.forEach((rule, i) => list.appendChild(targetRuleRow(rule, i)));
+  document.getElementById('addTargetRule').disabled = targetRules.length >= MAX_TARGET_RULES;
+}
+
+// targetRuleRow builds the controls of one rule; edits update the rule in
+// place and bubble up to the form, which schedules a new preview
+function targetRuleRow(rule, i) {
+  const row = document.createElement('div');
+  row.className = 'target-rule';
+  row.innerHTML = `
+    <div class="target-rule-head">
+      <input type="text" class="rule-selector" placeholder="e.g. Go, word:go, regex:[0-9]+"
+        title="Plain text or selectors: regex:[0-9]+, word:go, icase:go, nth:2:go, range:3-7">
+      <button type="button" class="small-btn rule-up" title="Move up">↑</button>
+      <button type="button" class="small-btn rule-down" title="Move down">↓</button>
+      <button type="button" class="small-btn rule-remove" title="Remove rule">&times;</button>
+    </div>
+    <div class="target-style">
+      <label class="inline">Color <input type="color" class="rule-color"></label>
+      <label class="inline"><input type="checkbox" class="rule-bg-on"> Background
+        <input type="color" class="rule-bg"></label>
+    </div>
+    <div class="attr-options">
+      ${TARGET_ATTRS.map(a => `<label><input type="checkbox" value="${a.value}"><span>${a.label}</span></label>`).join('')}
+    </div>`;
+
+  const selector = row.querySelector('.rule-selector');
+  const color = row.querySelector('.rule-color');
+  const bgOn = row.querySelector('.rule-bg-on');
+  const bg = row.querySelector('.rule-bg');
+  selector.value = rule.selector;
+  color.value = rule.color;
+  bgOn.checked = rule.bgEnabled;
+  bg.value = rule.bg;
+  row.querySelectorAll('.attr-options input').forEach(a => {
+    a.checked = rule.attrs.includes(a.value);
+  });
+
+  row.addEventListener('input', () => {
+    rule.selector = selector.value;
+    rule.color = color.value;
+    rule.bgEnabled = bgOn.checked;
+    rule.bg = bg.value;
+    rule.attrs = Array.from(row.querySelectorAll('.attr-options input:checked')).map(a => a.value);
+    saveTargetRules();
+  });
+
+  const up = row.querySelector('.rule-up');
+  const down = row.querySelector('.rule-down');
+  up.disabled = i === 0;
+  down.disabled = i === targetRules.length - 1;
+  up.addEventListener('click', () => {
+    [targetRules[i - 1], targetRules[i]] = [targetRules[i], targetRules[i - 1]];
+    changeTargetRules();
+  });
+  down.addEventListener('click', () => {
+    [targetRules[i], targetRules[i + 1]] = [targetRules[i + 1], targetRules[i]];
+    changeTargetRules();
+  });
+  row.querySelector('.rule-remove').addEventListener('click', () => {
+    targetRules.splice(i, 1);
+    if (!targetRules.length) targetRules.push(newTargetRule());
+    changeTargetRules();
+  });
+  return row;
+}
+
+// appendTargetRules adds the colorTarget/targetColor pairs of the rules
+// that have a selector, in priority order
+function appendTargetRules(fd) {
+  targetRules.forEach(rule => {
+    const selector = rule.selector.trim();
+    if (!selector) return;
+    fd.append('colorTarget', selector);
+    fd.append('targetColor', targetStyleSpec(rule));
+  });
+}
+
+// targetStyleSpec combines a rule's color, background and attributes into
+// the "fg/bg+attr" rule understood by the server
+function targetStyleSpec(rule) {
+  let spec = rule.color;
+  if (rule.bgEnabled) spec += '/' + rule.bg;
+  rule.attrs.forEach(a => {
+    spec += '+' + a;
+  });
+  return spec;
+}
@@ -0,0 +1,39 @@
+let themeCatalog = [];
+
+function initThemes() {
+  const select = document.getElementById('theme');
+  select.addEventListener('change', showThemePreview);
+
+  fetch('/themes')
+    .then(res => {
+      if (!res.ok) throw new Error('could not load themes');
+      return res.json();
+    })
+    .then(themes => {
+      themeCatalog = themes;
+      themes.forEach(t => {
+        const opt = document.createElement('option');
+        opt.value = t.name;
+        opt.textContent = t.name.charAt(0).toUpperCase() + t.name.slice(1);
+        select.appendChild(opt);
+      });
+    })
+    .catch(() => {
+      select.disabled = true;
+    });
+}
+
+// showThemePreview draws the swatches of the selected theme
+function showThemePreview() {
+  const preview = document.getElementById('themePreview');
+  const name = document.getElementById('theme').value;
+  const theme = themeCatalog.find(t => t.name === name);
+
+  preview.innerHTML = '';
+  if (!theme) return;
+  theme.colors.forEach(c => {
+    const swatch = document.createElement('span');
+    swatch.style.background = c;
+    preview.appendChild(swatch);
+  });
+}
@@ -1,23 +1,27 @@
 package utils
 
 import (
-	"strings"
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
 )
 
 // alignRows adjusts the position of ASCII art based on alignment type.
 // It no longer returns an error if width < content; it simply uses zero padding.
-func alignRows(rows []string, align string, width int) ([]string, error) {
+func alignRows(rows [][]canvas.Cell, align string, width int) ([][]canvas.Cell, error) {
 	// Left alignment → no padding
 	if align == "left" {
 		return rows, nil
 	}
 
-	// Measure the visible length (strip ANSI escapes)
-	lineLen := visibleLength(rows[0])
+	// Measure the visible length
+	lineLen := len(rows[0])
 
 	// Calculate pad, but never negative
 	pad := 0
 	switch align {
+	case "center":
+		if width > lineLen {
+			pad = (width - lineLen) / 2
+		}
 	case "right":
 		if width > lineLen {
 			pad = width - lineLen
@@ -30,8 +34,61 @@ func alignRows(rows []string, align string, width int) ([]string, error) {
 	// Prepend spaces to each row
 	for i := range rows {
 		if pad > 0 {
-			rows[i] = strings.Repeat(" ", pad) + rows[i]
+			rows[i] = append(canvas.Pad(pad), rows[i]...)
 		}
 	}
 	return rows, nil
 }
+
+// justifyRows stretches the gaps between words so the rows fill width
+// exactly. text is the full input the cells' Src offsets point into. A line
+// with a single word, or one already too wide, is left-aligned.
+func justifyRows(rows [][]canvas.Cell, text string, width int) [][]canvas.Cell {
+	// Locate the columns each word occupies (all rows share the same layout)
+	type span struct{ from, to int }
+	var words []span
+	inWord := false
+	for x, cell := range rows[0] {
+		isSpace := text[cell.Src] == ' '
+		switch {
+		case !isSpace && !inWord:
+			words = append(words, span{x, x + 1})
+			inWord = true
+		case !isSpace:
+			words[len(words)-1].to = x + 1
+		default:
+			inWord = false
+		}
+	}
+
+	totalWordLen := 0
+	for _, w := range words {
+		totalWordLen += w.to - w.from
+	}
+	slots := len(words) - 1
+	extra := width - totalWordLen
+	if slots <= 0 || extra < slots {
+		return rows
+	}
+
+	// Share the spare columns out, the first gaps taking one more
+	gaps := make([]int, slots)
+	for i := range gaps {
+		gaps[i] = extra / slots
+		if i < extra%slots {
+			gaps[i]++
+		}
+	}
+
+	// Stitch words and gaps identically across rows
+	result := make([][]canvas.Cell, len(rows))
+	for row := range rows {
+		for wi, w := range words {
+			result[row] = append(result[row], rows[row][w.from:w.to]...)
+			if wi < slots {
+				result[row] = append(result[row], canvas.Pad(gaps[wi])...)
+			}
+		}
+	}
+	return result
+}
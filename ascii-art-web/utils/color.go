@@ -2,157 +2,168 @@ package utils
 
 import (
 	"fmt"
-	"log"
-	"regexp"
-	"strconv"
+	"html"
 	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+	"platform.zone01.gr/git/askordal/ascii-art-core/paint"
 )
 
 type ColorTarget struct {
-	ColorCode string // hex format, e.g. "#ff0000"
+	ColorCode string // "fg/bg+attr", e.g. "#ff0000/navy+bold"
 	Substring string // substring to apply the color to ("" = global color)
 }
 
-// Converts a hex color "#rrggbb" into an ANSI 256-color escape code
-func parseColorCode(code string) string {
-	if strings.HasPrefix(code, "#") && len(code) == 7 {
-		r, err1 := strconv.ParseInt(code[1:3], 16, 0)
-		g, err2 := strconv.ParseInt(code[3:5], 16, 0)
-		b, err3 := strconv.ParseInt(code[5:7], 16, 0)
-		if err1 == nil && err2 == nil && err3 == nil {
-			return rgbToAnsi(int(r), int(g), int(b))
+// Converts a "fg/bg+attr" rule into a cell style. Colors are anything
+// color.Parse understands, e.g. "navy/#f00+bold".
+func parseStyle(spec string) (canvas.Style, error) {
+	fg, bg, attrs, err := canvas.SplitStyle(spec)
+	if err != nil {
+		return canvas.Style{}, err
+	}
+	style := canvas.Style{Attrs: attrs}
+	if fg != "" {
+		if style.FG, err = color.Parse(fg); err != nil {
+			return canvas.Style{}, err
 		}
 	}
-	return ""
-}
-
-// Converts RGB values to ANSI 256-color escape sequence
-func rgbToAnsi(r, g, b int) string {
-	r6 := r * 6 / 256
-	g6 := g * 6 / 256
-	b6 := b * 6 / 256
-	code := 16 + (36 * r6) + (6 * g6) + b6
-	return "\033[38;5;" + strconv.Itoa(code) + "m"
+	if bg != "" {
+		if style.BG, err = color.Parse(bg); err != nil {
+			return canvas.Style{}, err
+		}
+	}
+	return style, nil
 }
 
-// Converts ANSI 256-color escape sequences into <span style="color:#hex">
-var ansi256Pattern = regexp.MustCompile(`\x1b\[38;5;(\d{1,3})m`)
-
-func AnsiToHTML256(input string) (string, error) {
+// Converts text with ANSI escape codes, such as a saved .ans file, into
+// HTML for the preview. See canvas.FromANSI for the codes understood.
+func AnsiToHTML(input string) (string, error) {
 	if input == "" {
 		return "", fmt.Errorf("input string is empty")
 	}
+	return CanvasToHTML(canvas.FromANSI(input)), nil
+}
 
-	defer func() {
-		if r := recover(); r != nil {
-			// This catches any panic from regex or string ops
-			log.Printf("panic in AnsiToHTML256: %v", r)
-		}
-	}()
-
-	result := ansi256Pattern.ReplaceAllStringFunc(input, func(match string) string {
-		matches := ansi256Pattern.FindStringSubmatch(match)
-		if len(matches) != 2 {
-			log.Printf("invalid ANSI match: %q", match)
-			return "" // fail silently or return safe fallback
+// Converts a canvas into HTML-escaped text with a <span> for every change
+// of style. A span stays open across line breaks while the style holds, so
+// there are as few as possible, and every span is closed.
+func CanvasToHTML(c *canvas.Canvas) string {
+	var b strings.Builder
+	var open canvas.Style
+	for _, row := range c.Rows {
+		for _, run := range row.Runs() {
+			if run.Style != open {
+				if open != (canvas.Style{}) {
+					b.WriteString("</span>")
+				}
+				if run.Style != (canvas.Style{}) {
+					b.WriteString(styleSpan(run.Style))
+				}
+				open = run.Style
+			}
+			b.WriteString(html.EscapeString(run.Text))
 		}
-		hex := ansi256ToHex(matches[1])
-		return `<span style="color:` + hex + `">`
-	})
-
-	result = strings.ReplaceAll(result, "\033[0m", "</span>")
-	return result, nil
+		b.WriteByte('\n')
+	}
+	if open != (canvas.Style{}) {
+		b.WriteString("</span>")
+	}
+	return strings.ToValidUTF8(b.String(), "\uFFFD")
 }
 
-// Converts an ANSI 256-color index to its closest web-safe hex color
-func ansi256ToHex(code string) string {
-	n, err := strconv.Atoi(code)
-	if err != nil || n < 16 || n > 255 {
-		return "#000000" // fallback to black
-	}
-	c := n - 16
-	r := (c / 36) % 6 * 51
-	g := (c / 6 % 6) * 51
-	b := (c % 6) * 51
-	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
-}
+// Builds the opening <span> with inline CSS for a cell style
+func styleSpan(s canvas.Style) string {
+	var fg, bg string
+	var css []string
+	if s.Attrs&canvas.Bold != 0 {
+		css = append(css, "font-weight:bold")
+	}
+	if s.Attrs&canvas.Dim != 0 {
+		css = append(css, "opacity:0.6")
+	}
+	if s.Attrs&canvas.Italic != 0 {
+		css = append(css, "font-style:italic")
+	}
+	if s.Attrs&canvas.Underline != 0 {
+		css = append(css, "text-decoration:underline")
+	}
+	if s.FG.Valid() {
+		fg = s.FG.Hex()
+	}
+	if s.BG.Valid() {
+		bg = s.BG.Hex()
+	}
 
-// Removes ANSI escape codes from the string
-func stripANSICodes(s string) string {
-	result := ""
-	inEscape := false
-	for i := 0; i < len(s); i++ {
-		if s[i] == '\033' {
-			inEscape = true
-			continue
+	if s.Attrs&canvas.Reverse != 0 {
+		if fg == "" {
+			fg = "var(--ascii-fg)"
 		}
-		if inEscape {
-			if s[i] == 'm' {
-				inEscape = false
-			}
-			continue
+		if bg == "" {
+			bg = "var(--ascii-bg)"
 		}
-		result += string(s[i])
+		fg, bg = bg, fg
+	}
+	if fg != "" {
+		css = append(css, "color:"+fg)
+	}
+	if bg != "" {
+		css = append(css, "background-color:"+bg)
 	}
-	return result
-}
 
-// Returns the visible length of a string (ignoring ANSI codes)
-func visibleLength(s string) int {
-	return len(stripANSICodes(s))
+	span := `<span style="` + strings.Join(css, ";") + `"`
+	if s.Attrs&canvas.Blink != 0 {
+		span += ` class="ansi-blink"`
+	}
+	return span + ">"
 }
 
-// Builds ASCII art rows for a line of text and applies coloring rules
-func buildAsciiRowsWithColor(line string, banner BannerType, colorTargets []ColorTarget) ([]string, error) {
-	var globalColor string
+// Works out the style of every character of the input text. Target
+// substrings are selectors (see paint.Selector) matched across line breaks;
+// where they overlap the longest match wins, then the later target. Targets
+// without a substring paint the whole text, the last one winning.
+func textStyles(text string, colorTargets []ColorTarget) ([]canvas.Style, error) {
+	var globalStyle canvas.Style
 	for _, t := range colorTargets {
 		if t.Substring == "" {
-			globalColor = parseColorCode(t.ColorCode)
-			break
-		}
-	}
-
-	out := make([]string, blockLines)
-	i := 0
-
-	for i < len(line) {
-		matched := false
-		for _, t := range colorTargets {
-			if t.Substring == "" {
-				continue
-			}
-			if strings.HasPrefix(line[i:], t.Substring) {
-				segRows, err := buildAsciiRows(t.Substring, banner)
-				if err != nil {
-					return nil, err
-				}
-				colorCode := parseColorCode(t.ColorCode)
-				for r := 0; r < blockLines; r++ {
-					out[r] += colorCode + segRows[r] + "\033[0m"
-				}
-				i += len(t.Substring)
-				matched = true
-				break
+			style, err := parseStyle(t.ColorCode)
+			if err != nil {
+				return nil, err
 			}
+			globalStyle = style
 		}
+	}
 
-		if matched {
+	var rules []paint.Rule
+	for _, t := range colorTargets {
+		if t.Substring == "" {
 			continue
 		}
-
-		rows, err := buildAsciiRows(string(line[i]), banner)
+		sel, err := paint.ParseSelector(t.Substring)
 		if err != nil {
 			return nil, err
 		}
-		for r := 0; r < blockLines; r++ {
-			if globalColor != "" {
-				out[r] += globalColor + rows[r] + "\033[0m"
-			} else {
-				out[r] += rows[r]
-			}
+		style, err := parseStyle(t.ColorCode)
+		if err != nil {
+			return nil, err
 		}
-		i++
+		rules = append(rules, paint.Rule{Selector: sel, Style: style})
 	}
 
-	return out, nil
+	return paint.StyleText(text, globalStyle, rules), nil
+}
+
+// Builds ASCII art rows for a line of text and paints them with the styles
+// of the full input. offset is the position of line inside that input.
+func buildAsciiRowsWithColor(line string, offset int, fonts []BannerType, styles []canvas.Style) ([][]canvas.Cell, error) {
+	rows, err := buildAsciiRows(line, offset, fonts)
+	if err != nil {
+		return nil, err
+	}
+	for r := range rows {
+		for c := range rows[r] {
+			rows[r][c].Style = styles[rows[r][c].Src]
+		}
+	}
+	return rows, nil
 }
@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/markup"
+	"platform.zone01.gr/git/askordal/ascii-art-core/paint"
 )
 
 const (
@@ -60,48 +64,135 @@ func LoadBanner(fileName string) (BannerType, error) {
 	return banner, nil
 }
 
+// Options controls how AsciiArt lays out and colors the text
+type Options struct {
+	Align        string
+	ColorTargets []ColorTarget
+	Gradient     *paint.Gradient // replaces the global color when set
+	Palette      *paint.Palette  // theme colors; also replaces the global color
+	Width        int
+	Wrap         bool                  // break lines at spaces to fit Width
+	Markup       bool                  // parse inline {style}...{/} markup in the text
+	Banners      map[string]BannerType // banners markup may switch to by name
+}
+
 // AsciiArt renders the input string into ASCII art with alignment and color support
-func AsciiArt(input string, banner BannerType, align string, colorTargets []ColorTarget, width int) (string, error) {
+func AsciiArt(input string, banner BannerType, opts Options) (string, error) {
+	out, err := Render(input, banner, opts)
+	if err != nil {
+		return "", err
+	}
+	return out.ANSI(true), nil
+}
+
+// Render lays out and paints the input as a canvas, ready for any exporter
+func Render(input string, banner BannerType, opts Options) (*canvas.Canvas, error) {
 	input = strings.ReplaceAll(input, "\r", "")
-	var outputBuilder strings.Builder
+	out := &canvas.Canvas{}
+
+	// Strip inline markup first; it may pick colors, banners and alignment
+	var marks *markup.Result
+	if opts.Markup {
+		var err error
+		if marks, err = markup.Parse(input); err != nil {
+			return nil, err
+		}
+		input = marks.Text
+	}
 	lines := strings.Split(input, "\n")
 
-	for _, line := range lines {
+	colorTargets := opts.ColorTargets
+	if opts.Gradient != nil || opts.Palette != nil {
+		colorTargets = nil
+		for _, t := range opts.ColorTargets {
+			if t.Substring != "" {
+				colorTargets = append(colorTargets, t)
+			}
+		}
+	}
+
+	styles, err := textStyles(input, colorTargets)
+	if err != nil {
+		return nil, err
+	}
+	fonts := make([]BannerType, len(input))
+	for i := range fonts {
+		fonts[i] = banner
+		if marks != nil && marks.Fonts[i] != "" {
+			b, ok := opts.Banners[marks.Fonts[i]]
+			if !ok {
+				return nil, fmt.Errorf("markup: unknown font %q", marks.Fonts[i])
+			}
+			fonts[i] = b
+		}
+	}
+	if marks != nil {
+		markup.Merge(styles, marks.Styles)
+	}
+
+	offset := 0
+	for li, line := range lines {
+		start := offset
+		offset += len(line) + 1
 		if line == "" {
-			outputBuilder.WriteString("\n")
+			out.AddBlank()
 			continue
 		}
 
-		rows, err := buildAsciiRowsWithColor(line, banner, colorTargets)
-		if err != nil {
-			return "", err
+		// markup may align single lines differently
+		align := opts.Align
+		if marks != nil && marks.Aligns[li] != "" {
+			align = marks.Aligns[li]
 		}
 
-		rows, err = alignRows(rows, align, width)
-		if err != nil {
-			return "", err
+		pieces := []segment{{line, start}}
+		if opts.Wrap && opts.Width > 0 {
+			pieces = wrapLine(line, start, fonts, opts.Width)
 		}
+		for pi, piece := range pieces {
+			rows, err := buildAsciiRowsWithColor(piece.text, piece.offset, fonts, styles)
+			if err != nil {
+				return nil, err
+			}
+
+			// The last piece of a wrapped line ends a paragraph, so it
+			// isn't stretched
+			if align == "justify" && (pi < len(pieces)-1 || len(pieces) == 1) {
+				rows = justifyRows(rows, input, opts.Width)
+			} else if rows, err = alignRows(rows, align, opts.Width); err != nil {
+				return nil, err
+			}
 
-		for _, row := range rows {
-			outputBuilder.WriteString(row + "\n")
+			out.AddBand(rows)
 		}
 	}
-	return outputBuilder.String(), nil
+
+	if opts.Gradient != nil {
+		opts.Gradient.Apply(out)
+	}
+	if opts.Palette != nil {
+		opts.Palette.Apply(out, input)
+	}
+	return out, nil
 }
 
-// buildAsciiRows converts a single line of text to ASCII art rows
-func buildAsciiRows(line string, banner BannerType) ([]string, error) {
-	rows := make([]string, blockLines)
-	for _, ch := range line {
+// buildAsciiRows converts a single line of text to ASCII art rows.
+// offset is the position of line inside the full input text, and fonts
+// holds the banner of every character of that text.
+func buildAsciiRows(line string, offset int, fonts []BannerType) ([][]canvas.Cell, error) {
+	rows := make([][]canvas.Cell, blockLines)
+	for idx, ch := range line {
 		if ch < spaceAscii || ch > tildeAscii {
 			return nil, fmt.Errorf("unsupported character: %q", ch)
 		}
-		ascii, ok := banner[ch]
+		ascii, ok := fonts[offset+idx][ch]
 		if !ok || len(ascii) != blockLines {
 			return nil, fmt.Errorf("character %q not found in banner", ch)
 		}
 		for row := 0; row < blockLines; row++ {
-			rows[row] += ascii[row]
+			for j := 0; j < len(ascii[row]); j++ {
+				rows[row] = append(rows[row], canvas.Cell{Ch: ascii[row][j], Src: offset + idx})
+			}
 		}
 	}
 	return rows, nil
@@ -0,0 +1,44 @@
+package utils
+
+// segment is one piece of a wrapped line; offset is where its text starts
+// in the full input
+type segment struct {
+	text   string
+	offset int
+}
+
+// wrapLine breaks a line at spaces so every piece renders within width
+// columns. The space at each break is dropped, and a word wider than width
+// on its own is cut between characters. offset is the position of line in
+// the full input, whose characters fonts maps to their banners.
+func wrapLine(line string, offset int, fonts []BannerType, width int) []segment {
+	glyphWidth := func(i int) int {
+		if block, ok := fonts[offset+i][rune(line[i])]; ok && len(block) > 0 {
+			return len(block[0])
+		}
+		return 0
+	}
+
+	var pieces []segment
+	start, used := 0, 0 // current piece and its width so far
+	lastSpace := -1     // last space inside the current piece
+	for i := 0; i < len(line); i++ {
+		w := glyphWidth(i)
+		if used+w > width && i > start {
+			end, next := i, i
+			if lastSpace > start {
+				end, next = lastSpace, lastSpace+1
+			}
+			pieces = append(pieces, segment{line[start:end], offset + start})
+			start, used, lastSpace = next, 0, -1
+			for j := start; j < i; j++ {
+				used += glyphWidth(j)
+			}
+		}
+		if line[i] == ' ' {
+			lastSpace = i
+		}
+		used += w
+	}
+	return append(pieces, segment{line[start:], offset + start})
+}
@@ -0,0 +1,68 @@
+// Converts uploaded .ans files into HTML for the preview
+
+package web
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-web-export-file/utils"
+)
+
+// ansiMaxUpload limits the size of an uploaded file
+const ansiMaxUpload = 1 << 20
+
+// ansiExtensions are the files the upload accepts: colored art, or plain
+// art such as a .txt export
+var ansiExtensions = []string{".ans", ".txt"}
+
+// ansiUploadHandler converts the .ans file in the "file" field of a
+// multipart form into the same HTML spans as the live preview
+func ansiUploadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		renderErrorPage(w, r, http.StatusNotFound)
+		return
+	}
+	tooLarge := fmt.Sprintf("the file is limited to %d KiB", ansiMaxUpload>>10)
+	if r.ContentLength > ansiMaxUpload+64<<10 {
+		renderErrorWithMessage(w, http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+	// leave room for the multipart envelope around the file
+	r.Body = http.MaxBytesReader(w, r.Body, ansiMaxUpload+64<<10)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		renderErrorWithMessage(w, http.StatusBadRequest, "choose a .ans file to upload")
+		return
+	}
+	defer file.Close()
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	if !slices.Contains(ansiExtensions, ext) {
+		renderErrorWithMessage(w, http.StatusBadRequest, "only .ans and .txt files can be opened")
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, ansiMaxUpload+1))
+	if err != nil {
+		renderErrorWithMessage(w, http.StatusBadRequest, "the file could not be read")
+		return
+	}
+	if len(data) > ansiMaxUpload {
+		renderErrorWithMessage(w, http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+	out, err := utils.AnsiToHTML(string(data))
+	if err != nil {
+		renderErrorWithMessage(w, http.StatusBadRequest, "the file is empty")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(out))
+}
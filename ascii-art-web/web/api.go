@@ -0,0 +1,213 @@
+// JSON REST API for rendering, for programs rather than the browser form
+
+package web
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/export"
+	"platform.zone01.gr/git/askordal/ascii-art-core/paint"
+	"platform.zone01.gr/git/askordal/ascii-art-web-export-file/utils"
+)
+
+// Limits and defaults of the render API
+const (
+	apiMaxBody      = 2 << 20
+	apiMaxText      = 1_000_000
+	apiMaxWidth     = 1000
+	apiDefaultWidth = 150
+	apiMaxColors    = 100
+)
+
+// renderRequest is the body of POST /api/v1/render
+type renderRequest struct {
+	Text   string      `json:"text"`
+	Banner string      `json:"banner"`
+	Align  string      `json:"align"`
+	Width  int         `json:"width"`
+	Colors []colorRule `json:"colors"`
+	Format string      `json:"format"`
+	Markup bool        `json:"markup"`
+	Wrap   bool        `json:"wrap"`
+}
+
+// colorRule paints the text matched by Selector ("" for all of it) with a
+// "fg/bg+attr" style
+type colorRule struct {
+	Selector string `json:"selector"`
+	Style    string `json:"style"`
+}
+
+// renderResponse carries the exported art. Binary formats (images, PDF) are
+// base64 encoded, as Encoding says.
+type renderResponse struct {
+	Format      string   `json:"format"`
+	ContentType string   `json:"contentType"`
+	Encoding    string   `json:"encoding"`
+	Output      string   `json:"output"`
+	Columns     int      `json:"columns"`
+	Rows        int      `json:"rows"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// apiError is one problem with a request. Code is stable for programs to
+// match on; Field names the offending request field, when there is one.
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+type apiErrorResponse struct {
+	Errors []apiError `json:"errors"`
+}
+
+// apiRenderHandler renders JSON requests and answers in JSON, errors included
+func apiRenderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeAPIError(w, http.StatusMethodNotAllowed, apiError{Code: "method_not_allowed", Message: "use POST"})
+		return
+	}
+	if ct := r.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		writeAPIError(w, http.StatusUnsupportedMediaType,
+			apiError{Code: "unsupported_media_type", Message: "the body must be application/json"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, apiMaxBody))
+	if err != nil {
+		writeAPIError(w, http.StatusRequestEntityTooLarge,
+			apiError{Code: "body_too_large", Message: fmt.Sprintf("the body is limited to %d bytes", apiMaxBody)})
+		return
+	}
+
+	// Check the raw document against the published schema first, so every
+	// problem is reported the same way the OpenAPI document describes it
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiError{Code: "invalid_json", Message: err.Error()})
+		return
+	}
+	if problems := apiSchemas()["RenderRequest"].check("", doc); len(problems) > 0 {
+		writeAPIError(w, http.StatusBadRequest, problems...)
+		return
+	}
+	var req renderRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiError{Code: "invalid_json", Message: err.Error()})
+		return
+	}
+
+	opts, exporter, problems := req.validate()
+	if len(problems) > 0 {
+		writeAPIError(w, http.StatusBadRequest, problems...)
+		return
+	}
+
+	art, err := utils.Render(req.Text, LoadedBanners[req.Banner], opts)
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, apiError{Code: "render_failed", Message: err.Error(), Field: "text"})
+		return
+	}
+
+	var out bytes.Buffer
+	resp := renderResponse{Format: exporter.Name, ContentType: exporter.ContentType, Columns: art.Width(), Rows: len(art.Rows)}
+	exportOpts := export.Options{
+		TrueColor: true,
+		Meta:      export.Meta{Text: req.Text, Markup: req.Markup, Banner: req.Banner, Align: req.Align, Width: req.Width},
+		Warn:      func(msg string) { resp.Warnings = append(resp.Warnings, msg) },
+	}
+	if err := exporter.CheckSize(art, exportOpts, exportLimits); err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, apiError{Code: "art_too_large", Message: err.Error(), Field: "text"})
+		return
+	}
+	if err := exporter.Write(&out, art, exportOpts); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, apiError{Code: "export_failed", Message: err.Error()})
+		return
+	}
+	resp.Encoding, resp.Output = "utf-8", out.String()
+	if isBinary(exporter.ContentType) {
+		resp.Encoding, resp.Output = "base64", base64.StdEncoding.EncodeToString(out.Bytes())
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// validate fills in defaults and checks what the schema can't express,
+// collecting all the problems so a client can fix them in one go
+func (req *renderRequest) validate() (utils.Options, *export.Exporter, []apiError) {
+	var problems []apiError
+	fail := func(field, code, format string, args ...any) {
+		problems = append(problems, apiError{Code: code, Message: fmt.Sprintf(format, args...), Field: field})
+	}
+
+	req.Text = strings.ReplaceAll(req.Text, "\r", "")
+	if req.Banner == "" {
+		req.Banner = "standard"
+	}
+	if req.Align == "" {
+		req.Align = "left"
+	}
+	if req.Width == 0 {
+		req.Width = apiDefaultWidth
+	}
+
+	var targets []utils.ColorTarget
+	for i, rule := range req.Colors {
+		field := fmt.Sprintf("colors[%d]", i)
+		if rule.Selector != "" {
+			if _, err := paint.ParseSelector(rule.Selector); err != nil {
+				fail(field+".selector", "invalid_selector", "%v", err)
+			}
+		}
+		targets = append(targets, utils.ColorTarget{ColorCode: rule.Style, Substring: rule.Selector})
+	}
+
+	if req.Format == "" {
+		req.Format = "ansi"
+	}
+	exporter, err := export.Lookup(req.Format)
+	if err != nil {
+		fail("format", "unknown_format", "%v", err)
+	}
+
+	return utils.Options{
+		Align:        req.Align,
+		ColorTargets: targets,
+		Width:        req.Width,
+		Wrap:         req.Wrap,
+		Markup:       req.Markup,
+		Banners:      LoadedBanners,
+	}, exporter, problems
+}
+
+// validAligns lists the alignments the renderer understands
+var validAligns = map[string]bool{"left": true, "center": true, "right": true, "justify": true}
+
+// isBinary reports whether a content type can't travel as JSON text
+func isBinary(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/") && !strings.HasPrefix(contentType, "image/svg") ||
+		contentType == "application/pdf"
+}
+
+// writeJSON sends v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+// writeAPIError sends one or more problems as a JSON error response
+func writeAPIError(w http.ResponseWriter, status int, problems ...apiError) {
+	writeJSON(w, status, apiErrorResponse{Errors: problems})
+}
@@ -4,7 +4,11 @@ package web
 
 import (
 	"fmt"
+	"html/template"
+	"strconv"
 
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/paint"
 	"platform.zone01.gr/git/askordal/ascii-art-web-export-file/utils"
 )
 
@@ -16,13 +20,31 @@ type asciiRequest struct {
 	GlobalColor  string
 	ColorTargets []string
 	TargetColors []string
+	Gradient     string // colon-separated stops, e.g. "#ff0000:#0000ff"
+	GradientDir  string // column, row or char
+	Markup       bool   // text contains inline {style}...{/} markup
+	Theme        string // name from the theme catalog
+	ThemeBy      string // char, word or row
+	Seed         string // optional; picks theme colors at random, repeatably
+	Width        int    // columns to align in; 0 means the page width of 150
+	Wrap         bool   // break lines at spaces to fit Width
 }
 
 // GenerateAsciiArt generates ASCII art with colors and alignment (now exported)
 func generateAsciiArt(p *asciiRequest) (string, error) {
+	art, err := renderAscii(p)
+	if err != nil {
+		return "", err
+	}
+	return utils.CanvasToHTML(art), nil
+}
+
+// renderAscii lays out and paints the request as a canvas. Error messages
+// are HTML-escaped, ready for the error page.
+func renderAscii(p *asciiRequest) (*canvas.Canvas, error) {
 	bannerMap, ok := LoadedBanners[p.Banner]
 	if !ok {
-		return "", fmt.Errorf("internal: failed to load banner %q", p.Banner)
+		return nil, fmt.Errorf("internal: failed to load banner %q", p.Banner)
 	}
 
 	targets := []utils.ColorTarget{}
@@ -49,13 +71,63 @@ func generateAsciiArt(p *asciiRequest) (string, error) {
 		}}, targets...)
 	}
 
-	ascii, err := utils.AsciiArt(p.Text, bannerMap, p.Align, targets, 150)
+	if p.Width == 0 {
+		p.Width = 150
+	}
+	opts := utils.Options{
+		Align:        p.Align,
+		ColorTargets: targets,
+		Width:        p.Width,
+		Wrap:         p.Wrap,
+		Markup:       p.Markup,
+		Banners:      LoadedBanners,
+	}
+
+	// A gradient takes the place of the global color
+	if p.Gradient != "" {
+		g, err := paint.ParseGradient(p.Gradient, p.GradientDir)
+		if err != nil {
+			return nil, fmt.Errorf("%s", template.HTMLEscapeString(err.Error()))
+		}
+		opts.Gradient = g
+	}
+
+	// A theme also takes the place of the global color
+	if p.Theme != "" {
+		palette, err := buildPalette(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s", template.HTMLEscapeString(err.Error()))
+		}
+		opts.Palette = palette
+	}
+
+	art, err := utils.Render(p.Text, bannerMap, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error generating ASCII art: %s", template.HTMLEscapeString(err.Error()))
+	}
+	return art, nil
+}
+
+// buildPalette prepares the requested theme for painting
+func buildPalette(p *asciiRequest) (*paint.Palette, error) {
+	if p.Gradient != "" {
+		return nil, fmt.Errorf("a theme and a gradient cannot be combined")
+	}
+	theme, err := paint.FindTheme(LoadedThemes, p.Theme)
 	if err != nil {
-		return "", fmt.Errorf("error generating ASCII art: %w", err)
+		return nil, err
 	}
-	html, err := utils.AnsiToHTML256(ascii)
+	palette, err := paint.NewPalette(theme, p.ThemeBy)
 	if err != nil {
-		return "", fmt.Errorf("error converting ANSI to HTML: %w", err)
+		return nil, err
+	}
+	if p.Seed != "" {
+		seed, err := strconv.ParseInt(p.Seed, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seed %q: must be a whole number", p.Seed)
+		}
+		palette.Random = true
+		palette.Seed = seed
 	}
-	return html, nil
+	return palette, nil
 }
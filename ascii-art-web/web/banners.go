@@ -11,10 +11,12 @@ import (
 	"platform.zone01.gr/git/askordal/ascii-art-web-export-file/utils"
 )
 
-var LoadedBanners map[string]map[rune][]string
+var LoadedBanners map[string]utils.BannerType
 
-func init() {
-	LoadedBanners = make(map[string]map[rune][]string)
+// loadBanners reads every banner of the banners directory; StartServer
+// calls it, so importing the package doesn't depend on the working directory
+func loadBanners() {
+	LoadedBanners = make(map[string]utils.BannerType)
 	files, err := os.ReadDir("banners")
 	if err != nil {
 		log.Fatalf("error reading banners dir: %v", err)
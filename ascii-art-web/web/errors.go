@@ -14,8 +14,13 @@ type ErrorData struct {
 	Message template.HTML
 }
 
-// Preload error template at startup
-var errorTemplate = template.Must(template.ParseFiles("templates/error.html"))
+// Error template, preloaded at startup by StartServer
+var errorTemplate *template.Template
+
+// loadErrorTemplate parses the error page template
+func loadErrorTemplate() {
+	errorTemplate = template.Must(template.ParseFiles("templates/error.html"))
+}
 
 // errorPageHandler reads ?code= from URL and shows the appropriate error page
 func errorPageHandler(w http.ResponseWriter, r *http.Request) {
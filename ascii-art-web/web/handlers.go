@@ -3,13 +3,18 @@
 package web
 
 import (
+	"bytes"
 	"fmt"
 	"html/template"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+	"platform.zone01.gr/git/askordal/ascii-art-core/export"
 )
 
 // indexHandler serves the homepage (index.html)
@@ -35,8 +40,13 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 
 // asciiArtHandler handles the form submission for ASCII art generation.
 func asciiArtHandler(w http.ResponseWriter, r *http.Request) {
-	// Redirect GET requests to the homepage to prevent resubmission on refresh
+	// GET renders from query parameters; without any it redirects to the
+	// homepage to prevent resubmission on refresh
 	if r.Method == http.MethodGet {
+		if len(r.URL.Query()) > 0 {
+			renderQueryHandler(w, r)
+			return
+		}
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -48,7 +58,7 @@ func asciiArtHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract and validate form parameters from the POST request
-	params, err := extractAsciiParams(r)
+	params, err := extractAsciiParams(r, "AsciiArtForm")
 	if err != nil {
 		renderErrorWithMessage(w, http.StatusBadRequest, err.Error())
 		return
@@ -57,12 +67,7 @@ func asciiArtHandler(w http.ResponseWriter, r *http.Request) {
 	// Generate ASCII art based on the extracted parameters
 	ascii, err := generateAsciiArt(params)
 	if err != nil {
-		// Handle internal vs. client-caused errors differently
-		if strings.HasPrefix(err.Error(), "internal:") {
-			renderErrorWithMessage(w, http.StatusInternalServerError, strings.TrimPrefix(err.Error(), "internal: "))
-		} else {
-			renderErrorWithMessage(w, http.StatusBadRequest, err.Error())
-		}
+		renderGenerateError(w, err)
 		return
 	}
 
@@ -72,6 +77,16 @@ func asciiArtHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(ascii))
 }
 
+// renderGenerateError shows a failed render, telling internal errors apart
+// from client-caused ones
+func renderGenerateError(w http.ResponseWriter, err error) {
+	if strings.HasPrefix(err.Error(), "internal:") {
+		renderErrorWithMessage(w, http.StatusInternalServerError, strings.TrimPrefix(err.Error(), "internal: "))
+		return
+	}
+	renderErrorWithMessage(w, http.StatusBadRequest, err.Error())
+}
+
 // asciiTableHandler serves the ASCII table reference page
 func asciiTableHandler(w http.ResponseWriter, r *http.Request) {
 	path := filepath.Join("templates", "ascii_table.html")
@@ -86,26 +101,24 @@ func asciiTableHandler(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, path)
 }
 
-// extractAsciiParams parses and validates user input from the form
-func extractAsciiParams(r *http.Request) (*asciiRequest, error) {
-	// Normalize text input and validate length
-	text := strings.ReplaceAll(r.FormValue("inputText"), "\r", "")
-	if len(text) > 1_000_000 {
-		return nil, fmt.Errorf("input too long - max is 1,000,000")
-	}
-
-	// Ensure only ASCII characters (except newline) are present
-	for _, ch := range text {
-		if ch != '\n' && (ch < 32 || ch > 126) {
+// extractAsciiParams parses and validates user input from the form against
+// the named schema of the OpenAPI document
+func extractAsciiParams(r *http.Request, schemaName string) (*asciiRequest, error) {
+	if problems := checkRequestForm(r, schemaName); len(problems) > 0 {
+		switch p := problems[0]; p.Code {
+		case "text_too_long":
+			return nil, fmt.Errorf("input too long - max is 1,000,000")
+		case "unsupported_character":
 			return nil, fmt.Errorf(`only ASCII characters are allowed - <a href="/ascii-table"><u>see the full ASCII chart HERE</u></a>`)
+		case "missing_field":
+			return nil, fmt.Errorf("missing text or banner")
+		default:
+			return nil, fmt.Errorf("%s", template.HTMLEscapeString(p.Message))
 		}
 	}
-
-	// Get banner style and ensure both text and banner are present
+	text := strings.ReplaceAll(r.FormValue("inputText"), "\r", "")
 	banner := r.FormValue("banner")
-	if text == "" || banner == "" {
-		return nil, fmt.Errorf("missing text or banner")
-	}
+	width, _ := strconv.Atoi(r.FormValue("width"))
 
 	// Support for optional color highlighting for specific words
 	colorTargets := r.Form["colorTarget"]
@@ -119,6 +132,14 @@ func extractAsciiParams(r *http.Request) (*asciiRequest, error) {
 		GlobalColor:  r.FormValue("color"),
 		ColorTargets: colorTargets,
 		TargetColors: targetColors,
+		Gradient:     r.FormValue("gradient"),
+		GradientDir:  r.FormValue("gradientDirection"),
+		Markup:       r.FormValue("markup") == "on",
+		Theme:        r.FormValue("theme"),
+		ThemeBy:      r.FormValue("themeBy"),
+		Seed:         r.FormValue("seed"),
+		Width:        width,
+		Wrap:         r.FormValue("wrap") == "on",
 	}, nil
 }
 
@@ -135,6 +156,30 @@ func withRecover(h http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// exportLimits bounds the raster, animated and paged exports, which take
+// far more memory than the art itself: every cell of a PNG or GIF frame is
+// hundreds of pixels
+var exportLimits = export.Limits{Cells: 50_000, FrameCells: 250_000}
+
+// Longest frame delay and most loops an animated export may ask for
+const (
+	exportMaxDelay = 60_000 // milliseconds
+	exportMaxLoops = 65_535 // the most a GIF can store
+)
+
+// formInt reads an optional whole-number form field; a missing one is 0
+func formInt(r *http.Request, name string) (int, error) {
+	v := r.FormValue(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a whole number, not %q", name, v)
+	}
+	return n, nil
+}
+
 // handleExport handles exporting the generated ASCII art in various formats
 func handleExport(w http.ResponseWriter, r *http.Request) {
 	// Only allow POST method for exporting
@@ -143,10 +188,16 @@ func handleExport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get ASCII art from form data
-	text := r.FormValue("asciiText")
-	if text == "" {
-		renderErrorWithMessage(w, http.StatusBadRequest, "Empty output, nothing to export.")
+	// Render the art again from the same fields as the preview, so the file
+	// keeps its colors and alignment
+	params, err := extractAsciiParams(r, "ExportForm")
+	if err != nil {
+		renderErrorWithMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	art, err := renderAscii(params)
+	if err != nil {
+		renderGenerateError(w, err)
 		return
 	}
 
@@ -159,42 +210,64 @@ func handleExport(w http.ResponseWriter, r *http.Request) {
 	if format == "" {
 		format = "txt"
 	}
-
-	// Determine MIME type based on format
-	contentType := map[string]string{
-		"txt":  "text/plain; charset=utf-8",
-		"html": "text/html; charset=utf-8",
-		"json": "application/json",
-		"svg":  "image/svg+xml",
-	}[format]
-
-	// Fallback to default format if unknown
-	if contentType == "" {
-		contentType = "application/octet-stream"
-		format = "txt"
+	e, err := export.Lookup(format)
+	if err != nil {
+		renderErrorWithMessage(w, http.StatusBadRequest, template.HTMLEscapeString(err.Error()))
+		return
 	}
 
-	var output string
+	var output bytes.Buffer
+	opts := export.Options{
+		TrueColor:   true,
+		Meta:        export.Meta{Text: params.Text, Markup: params.Markup, Banner: params.Banner, Align: params.Align, Width: params.Width},
+		SVGPixels:   r.FormValue("svgPixels") == "on",
+		Transparent: r.FormValue("transparent") == "on",
 
-	// Format output based on selected format
-	switch format {
-	case "json":
-		output = fmt.Sprintf(`{"ascii":"%s"}`, template.JSEscapeString(text))
-	case "html":
-		output = "<pre>" + template.HTMLEscapeString(text) + "</pre>"
-	case "svg":
-		// Embed ASCII text in SVG format for vector export
-		output = fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="100%%" height="100%%">
-  <text x="0" y="15" font-family="monospace" font-size="14">%s</text>
-</svg>`, template.HTMLEscapeString(text))
-	default:
-		output = text
+		Paper:  r.FormValue("paper"),
+		Poster: r.FormValue("poster") == "on",
+
+		HTMLTheme:    r.FormValue("htmlTheme"),
+		HTMLFragment: r.FormValue("htmlFragment") == "on",
+		InlineCSS:    r.FormValue("inlineCss") == "on",
+	}
+	var warnings []string
+	opts.Warn = func(msg string) { warnings = append(warnings, msg) }
+	if bg := r.FormValue("background"); bg != "" {
+		c, err := color.Parse(bg)
+		if err != nil {
+			renderErrorWithMessage(w, http.StatusBadRequest, template.HTMLEscapeString(err.Error()))
+			return
+		}
+		opts.Background = c
+	}
+	opts.Animation = r.FormValue("animation")
+	opts.GIFPalette = r.FormValue("gifPalette")
+	if opts.Delay, err = formInt(r, "delay"); err != nil {
+		renderErrorWithMessage(w, http.StatusBadRequest, template.HTMLEscapeString(err.Error()))
+		return
+	}
+	if opts.Loops, err = formInt(r, "loops"); err != nil {
+		renderErrorWithMessage(w, http.StatusBadRequest, template.HTMLEscapeString(err.Error()))
+		return
+	}
+	if err := e.CheckSize(art, opts, exportLimits); err != nil {
+		renderErrorWithMessage(w, http.StatusBadRequest, template.HTMLEscapeString(err.Error()))
+		return
+	}
+	if err := e.Write(&output, art, opts); err != nil {
+		renderErrorWithMessage(w, http.StatusInternalServerError, "Export failed.")
+		return
 	}
 
 	// Set headers and deliver file as a downloadable attachment
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", filename, format))
-	w.Header().Set("Content-Length", strconv.Itoa(len(output)))
+	if len(warnings) > 0 {
+		w.Header().Set("X-Export-Warning", strings.Join(warnings, "; "))
+	}
+	w.Header().Set("Content-Type", e.ContentType)
+	// FormatMediaType quotes the name, or percent-encodes it when it has
+	// control or non-ASCII characters, so it can't break out of the header
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename + "." + e.Ext}))
+	w.Header().Set("Content-Length", strconv.Itoa(output.Len()))
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(output))
+	w.Write(output.Bytes())
 }
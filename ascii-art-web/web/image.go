@@ -0,0 +1,113 @@
+// Serves rendered banners as cacheable images for <img> tags
+
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+	"platform.zone01.gr/git/askordal/ascii-art-core/export"
+)
+
+// Limits of the image endpoints, which anyone can hotlink
+const (
+	imageMaxQuery = 2048   // bytes of query string
+	imageMaxText  = 200    // characters of text
+	imageMaxCells = 20_000 // columns × rows of the rendered art
+)
+
+// imageCacheControl lets browsers and proxies keep an image for a day; the
+// same query always renders the same image
+const imageCacheControl = "public, max-age=86400"
+
+// imageHandler serves GET /render.<format>?text=... with the named exporter
+func imageHandler(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			imageError(w, http.StatusMethodNotAllowed, "use GET")
+			return
+		}
+		if len(r.URL.RawQuery) > imageMaxQuery {
+			imageError(w, http.StatusRequestURITooLong, fmt.Sprintf("the query is limited to %d bytes", imageMaxQuery))
+			return
+		}
+		query := r.URL.Query()
+		if problems := apiSchemas()["ImageQuery"].checkForm(query); len(problems) > 0 {
+			imageError(w, http.StatusBadRequest, problems[0].Message)
+			return
+		}
+
+		p := queryRequest(query)
+		art, err := renderAscii(p)
+		if err != nil {
+			imageError(w, http.StatusBadRequest, html.UnescapeString(err.Error()))
+			return
+		}
+		if cells := art.Width() * len(art.Rows); cells > imageMaxCells {
+			imageError(w, http.StatusBadRequest, fmt.Sprintf("the art is %d cells; images are limited to %d", cells, imageMaxCells))
+			return
+		}
+
+		opts := export.Options{
+			TrueColor:   true,
+			Meta:        export.Meta{Text: p.Text, Markup: p.Markup, Banner: p.Banner, Align: p.Align, Width: p.Width},
+			SVGPixels:   query.Get("pixels") != "",
+			Transparent: query.Get("transparent") != "",
+		}
+		if bg := query.Get("background"); bg != "" {
+			if opts.Background, err = color.Parse(bg); err != nil {
+				imageError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		e, _ := export.Lookup(format)
+		var out bytes.Buffer
+		if err := e.Write(&out, art, opts); err != nil {
+			imageError(w, http.StatusInternalServerError, "export failed")
+			return
+		}
+
+		sum := sha256.Sum256(out.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", imageCacheControl)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", e.ContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(out.Len()))
+		if r.Method == http.MethodHead {
+			return
+		}
+		w.Write(out.Bytes())
+	}
+}
+
+// etagMatches reports whether an If-None-Match header names the tag
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// imageError answers a failed image request with one line of text that is
+// never cached
+func imageError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "error: %s\n", message)
+}
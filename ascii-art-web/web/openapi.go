@@ -0,0 +1,405 @@
+// Publishes the server's contract as an OpenAPI 3 document
+
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/export"
+)
+
+// textPattern allows printable ASCII and line breaks
+const textPattern = `^[ -~\r\n]*$`
+
+var (
+	schemasOnce sync.Once
+	schemas     map[string]*schema
+)
+
+// apiSchemas returns the named schemas of the document. They are built on
+// first use, once the banners and themes are loaded, and are what the
+// handlers validate requests against.
+func apiSchemas() map[string]*schema {
+	schemasOnce.Do(func() {
+		banners := make([]string, 0, len(LoadedBanners))
+		for name := range LoadedBanners {
+			banners = append(banners, name)
+		}
+		sort.Strings(banners)
+		themes := make([]string, len(LoadedThemes))
+		for i, t := range LoadedThemes {
+			themes[i] = t.Name
+		}
+		aligns := make([]string, 0, len(validAligns))
+		for name := range validAligns {
+			aligns = append(aligns, name)
+		}
+		sort.Strings(aligns)
+		text := func(codes map[string]string) *schema {
+			return &schema{
+				Type: "string", MinLength: intPtr(1), MaxLength: intPtr(apiMaxText),
+				Pattern: textPattern, hint: "only printable ASCII characters and newlines are allowed",
+				Codes: codes,
+			}
+		}
+		checkbox := &schema{Type: "string", Enum: []string{"on"}, Description: "Present when the box is ticked"}
+		colorCodes := map[string]string{"format": "invalid_color"}
+
+		schemas = map[string]*schema{
+			"RenderRequest": {
+				Type:                 "object",
+				Required:             []string{"text"},
+				AdditionalProperties: boolPtr(false),
+				Properties: map[string]*schema{
+					"text": text(map[string]string{"minLength": "missing_field", "maxLength": "text_too_long", "pattern": "unsupported_character"}),
+					"banner": {Type: "string", Enum: banners, Default: "standard",
+						Codes: map[string]string{"enum": "unknown_banner"}},
+					"align": {Type: "string", Enum: aligns, Default: "left",
+						Codes: map[string]string{"enum": "invalid_align"}},
+					"width": {Type: "integer", Minimum: intPtr(1), Maximum: intPtr(apiMaxWidth), Default: apiDefaultWidth,
+						Description: "Columns to align in",
+						Codes:       map[string]string{"minimum": "invalid_width", "maximum": "invalid_width"}},
+					"colors": {Type: "array", Items: ref("ColorRule"), MaxItems: intPtr(apiMaxColors),
+						Description: "Rules applied in order. Where selectors overlap the longest match wins, then the later rule; " +
+							"of the rules with an empty selector, the last one paints the whole text"},
+					"format": {Type: "string", Enum: export.Formats(), Default: "ansi",
+						Description: "png, gif, cast and pdf take art of at most 50,000 cells, and 250,000 summed over every frame or page; " +
+							"PNG and GIF images at most 65,535 pixels a side. Larger art is refused with art_too_large.",
+						Codes: map[string]string{"enum": "unknown_format"}},
+					"markup": {Type: "boolean", Default: false, Description: "Read inline {style}...{/} markup in the text"},
+					"wrap":   {Type: "boolean", Default: false, Description: "Break lines at spaces so the art fits width"},
+				},
+			},
+			"ColorRule": {
+				Type:                 "object",
+				Required:             []string{"style"},
+				AdditionalProperties: boolPtr(false),
+				Properties: map[string]*schema{
+					"selector": {Type: "string", Default: "",
+						Description: "Text to paint: a literal, or regex:, word:, icase:, nth:, range: or lit: selectors. Empty paints everything."},
+					"style": {Type: "string", Format: "style", MinLength: intPtr(1),
+						Description: `"fg/bg+attr", e.g. "red/#000000+bold"; attributes are bold, dim, italic, underline, blink and reverse`,
+						Codes:       map[string]string{"minLength": "invalid_color", "format": "invalid_color"}},
+				},
+			},
+			"RenderResponse": {
+				Type:     "object",
+				Required: []string{"format", "contentType", "encoding", "output", "columns", "rows"},
+				Properties: map[string]*schema{
+					"format":      {Type: "string"},
+					"contentType": {Type: "string"},
+					"encoding":    {Type: "string", Enum: []string{"utf-8", "base64"}, Description: "base64 for images and PDF"},
+					"output":      {Type: "string"},
+					"columns":     {Type: "integer"},
+					"rows":        {Type: "integer"},
+					"warnings":    {Type: "array", Items: &schema{Type: "string"}},
+				},
+			},
+			"Error": {
+				Type:     "object",
+				Required: []string{"code", "message"},
+				Properties: map[string]*schema{
+					"code":    {Type: "string", Description: "Stable, machine-readable reason, e.g. unknown_banner"},
+					"message": {Type: "string"},
+					"field":   {Type: "string", Description: "Path of the offending field, e.g. colors[1].style"},
+				},
+			},
+			"ErrorResponse": {
+				Type:       "object",
+				Required:   []string{"errors"},
+				Properties: map[string]*schema{"errors": {Type: "array", Items: ref("Error")}},
+			},
+			"AsciiArtForm": {
+				Type:     "object",
+				Required: []string{"inputText", "banner"},
+				Properties: map[string]*schema{
+					"inputText":         text(map[string]string{"minLength": "missing_field", "maxLength": "text_too_long", "pattern": "unsupported_character"}),
+					"banner":            {Type: "string", Enum: banners, Codes: map[string]string{"enum": "unknown_banner"}},
+					"align":             {Type: "string", Enum: aligns, Default: "left"},
+					"color":             {Type: "string", Format: "style", Description: "Style of the whole text", Codes: colorCodes},
+					"colorTarget":       {Type: "array", Items: &schema{Type: "string"}, Description: "Selectors, paired by position with targetColor"},
+					"targetColor":       {Type: "array", Items: &schema{Type: "string", Format: "style", Codes: colorCodes}},
+					"gradient":          {Type: "string", Description: `Colon-separated stops, e.g. "#ff0000:#0000ff"`},
+					"gradientDirection": {Type: "string", Enum: []string{"column", "row", "char"}, Default: "column"},
+					"markup":            checkbox,
+					"theme":             {Type: "string", Enum: themes},
+					"themeBy":           {Type: "string", Enum: []string{"char", "word", "row"}, Default: "char"},
+					"seed":              {Type: "string", Pattern: `^-?[0-9]+$`, hint: "must be a whole number"},
+					"width": {Type: "integer", Minimum: intPtr(1), Maximum: intPtr(apiMaxWidth), Default: apiDefaultWidth,
+						Description: "Columns to align and wrap in; the preview sends the columns that fit its pane"},
+					"wrap": {Type: "string", Enum: []string{"on"}, Description: "Break lines at spaces so the art fits width"},
+				},
+			},
+			"ExportForm": {
+				Type:     "object",
+				Required: []string{"inputText", "banner"},
+				Properties: map[string]*schema{
+					"format":       {Type: "string", Enum: export.Formats(), Default: "txt"},
+					"filename":     {Type: "string", Default: "ascii-art-web-export"},
+					"svgPixels":    checkbox,
+					"transparent":  checkbox,
+					"background":   {Type: "string", Format: "color", Description: "Page color for images and HTML", Codes: colorCodes},
+					"paper":        {Type: "string", Enum: export.Papers(), Default: "a4"},
+					"poster":       checkbox,
+					"htmlTheme":    {Type: "string", Enum: export.HTMLThemes(), Default: "auto"},
+					"htmlFragment": checkbox,
+					"inlineCss":    checkbox,
+					"animation":    {Type: "string", Enum: export.Animations()},
+					"delay":        {Type: "integer", Minimum: intPtr(0), Maximum: intPtr(exportMaxDelay), Description: "Milliseconds per frame"},
+					"loops":        {Type: "integer", Minimum: intPtr(0), Maximum: intPtr(exportMaxLoops), Description: "0 repeats forever"},
+					"gifPalette":   {Type: "string", Enum: export.GIFPalettes(), Default: "auto"},
+				},
+			},
+			"Theme": {
+				Type:     "object",
+				Required: []string{"name", "colors"},
+				Properties: map[string]*schema{
+					"name":   {Type: "string"},
+					"colors": {Type: "array", Items: &schema{Type: "string"}},
+				},
+			},
+		}
+
+		// /export re-renders from the same fields as the preview
+		for name, prop := range schemas["AsciiArtForm"].Properties {
+			schemas["ExportForm"].Properties[name] = prop
+		}
+
+		// GET /ascii-art takes the form fields as query parameters, with a
+		// shorter name for the text and a width for terminals
+		query := &schema{Type: "object", Required: []string{"text"}, Properties: map[string]*schema{}}
+		for name, prop := range schemas["AsciiArtForm"].Properties {
+			query.Properties[name] = prop
+		}
+		delete(query.Properties, "inputText")
+		query.Properties["text"] = text(map[string]string{"minLength": "missing_field", "maxLength": "text_too_long", "pattern": "unsupported_character"})
+		query.Properties["banner"] = &schema{Type: "string", Enum: banners, Default: "standard", Codes: map[string]string{"enum": "unknown_banner"}}
+		query.Properties["markup"] = &schema{Type: "string", Enum: []string{"on", "1", "true"}}
+		query.Properties["wrap"] = &schema{Type: "string", Enum: []string{"on", "1", "true"}, Description: "Break lines at spaces so the art fits width"}
+		schemas["AsciiArtQuery"] = query
+
+		// The image endpoints take the same parameters with a tighter text
+		// limit, plus image settings
+		image := &schema{Type: "object", Required: []string{"text"}, Properties: map[string]*schema{}}
+		for name, prop := range query.Properties {
+			image.Properties[name] = prop
+		}
+		image.Properties["text"] = &schema{
+			Type: "string", MinLength: intPtr(1), MaxLength: intPtr(imageMaxText),
+			Pattern: textPattern, hint: "only printable ASCII characters and newlines are allowed",
+			Codes: map[string]string{"minLength": "missing_field", "maxLength": "text_too_long", "pattern": "unsupported_character"},
+		}
+		image.Properties["background"] = &schema{Type: "string", Format: "color", Description: "PNG page color", Codes: colorCodes}
+		image.Properties["transparent"] = &schema{Type: "string", Enum: []string{"on", "1", "true"}, Description: "PNG without a page color"}
+		image.Properties["pixels"] = &schema{Type: "string", Enum: []string{"on", "1", "true"}, Description: "SVG cells as filled squares instead of text"}
+		schemas["ImageQuery"] = image
+
+		schemas["AnsiUpload"] = &schema{
+			Type:     "object",
+			Required: []string{"file"},
+			Properties: map[string]*schema{
+				"file": {Type: "string", Format: "binary", Description: "A .ans or .txt file of at most 1 MiB"},
+			},
+		}
+	})
+	return schemas
+}
+
+// openAPIDocument describes every route of the server
+func openAPIDocument() map[string]any {
+	content := func(mediaType string, s *schema) map[string]any {
+		return map[string]any{mediaType: map[string]any{"schema": s}}
+	}
+	reply := func(description, mediaType string, s *schema) map[string]any {
+		r := map[string]any{"description": description}
+		if mediaType != "" {
+			r["content"] = content(mediaType, s)
+		}
+		return r
+	}
+	page := func(description string) map[string]any {
+		return reply(description, "text/html", &schema{Type: "string"})
+	}
+	failure := func(description string) map[string]any {
+		return reply(description, "application/json", ref("ErrorResponse"))
+	}
+	form := func(name string) map[string]any {
+		return map[string]any{"required": true, "content": content("application/x-www-form-urlencoded", ref(name))}
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "ASCII Art Web",
+			"version":     "1.0.0",
+			"description": "Renders text as ASCII art banners, for the browser form and for programs.",
+		},
+		"paths": map[string]any{
+			"/": map[string]any{"get": map[string]any{
+				"summary":   "Main page",
+				"responses": map[string]any{"200": page("The generator UI")},
+			}},
+			"/ascii-art": map[string]any{
+				"post": map[string]any{
+					"summary":     "Render the form for the live preview",
+					"requestBody": form("AsciiArtForm"),
+					"responses": map[string]any{
+						"200": page("The art as HTML spans"),
+						"400": page("Error page explaining the invalid input"),
+					},
+				},
+				"get": map[string]any{
+					"summary": "Render from query parameters, e.g. curl localhost:8080/ascii-art?text=hi",
+					"description": "An Accept header preferring text/plain gets text without colors and one preferring text/html a page. " +
+						"Otherwise curl, wget and HTTPie get ANSI colors and other clients a page. Without any parameters the request is redirected to /.",
+					"parameters": queryParameters(apiSchemas()["AsciiArtQuery"]),
+					"responses": map[string]any{
+						"200": map[string]any{
+							"description": "The art",
+							"content": map[string]any{
+								"text/plain": map[string]any{"schema": &schema{Type: "string"}},
+								"text/html":  map[string]any{"schema": &schema{Type: "string"}},
+							},
+						},
+						"303": reply("No parameters: redirect to /", "", nil),
+						"400": reply("Invalid parameters, as an error page or one line of text", "text/plain", &schema{Type: "string"}),
+					},
+				},
+			},
+			"/export": map[string]any{"post": map[string]any{
+				"summary":     "Render the form again and download it in one of the export formats",
+				"requestBody": form("ExportForm"),
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "The file, as an attachment",
+						"headers": map[string]any{
+							"Content-Disposition": map[string]any{"schema": &schema{Type: "string"}},
+							"X-Export-Warning":    map[string]any{"description": "Notes from the exporter, joined with \"; \"", "schema": &schema{Type: "string"}},
+						},
+						"content": content("application/octet-stream", &schema{Type: "string", Format: "binary"}),
+					},
+					"400": page("Error page explaining the invalid input"),
+				},
+			}},
+			"/ansi": map[string]any{"post": map[string]any{
+				"summary": "Convert an uploaded .ans file into HTML spans for the preview",
+				"description": "SGR codes for 16, 256 and truecolor foreground and background, bold, dim, italic, underline, " +
+					"blink, reverse and their resets are kept; other escape sequences are dropped.",
+				"requestBody": map[string]any{"required": true, "content": content("multipart/form-data", ref("AnsiUpload"))},
+				"responses": map[string]any{
+					"200": page("The art as HTML spans"),
+					"400": page("Error page: no file, an unsupported file type or an empty file"),
+					"413": page("Error page: the file is too large"),
+				},
+			}},
+			"/ascii-table": map[string]any{"get": map[string]any{
+				"summary":   "Table of the supported characters",
+				"responses": map[string]any{"200": page("The ASCII table")},
+			}},
+			"/themes": map[string]any{"get": map[string]any{
+				"summary":   "Color theme catalog",
+				"responses": map[string]any{"200": reply("Built-in and user themes", "application/json", &schema{Type: "array", Items: ref("Theme")})},
+			}},
+			"/error": map[string]any{"get": map[string]any{
+				"summary": "Error page for a status code",
+				"parameters": []map[string]any{
+					{"name": "code", "in": "query", "schema": &schema{Type: "integer"}},
+				},
+				"responses": map[string]any{"default": page("The error page")},
+			}},
+			"/static/{path}": map[string]any{"get": map[string]any{
+				"summary": "Scripts, styles and images of the UI",
+				"parameters": []map[string]any{
+					{"name": "path", "in": "path", "required": true, "schema": &schema{Type: "string"}},
+				},
+				"responses": map[string]any{"200": reply("The file", "", nil), "404": reply("No such file", "", nil)},
+			}},
+			"/render.svg": imagePath("SVG", "image/svg+xml"),
+			"/render.png": imagePath("PNG", "image/png"),
+			"/api/v1/render": map[string]any{"post": map[string]any{
+				"summary": "Render text and export it in any format",
+				"requestBody": map[string]any{
+					"required": true,
+					"content":  content("application/json", ref("RenderRequest")),
+				},
+				"responses": map[string]any{
+					"200": reply("The exported art", "application/json", ref("RenderResponse")),
+					"400": failure("The request breaks the schema; every problem is listed"),
+					"405": failure("Only POST is allowed"),
+					"413": failure("The body is too large"),
+					"415": failure("The body is not JSON"),
+					"422": failure("The text could not be rendered, e.g. broken markup, or the art is too large for the format"),
+					"500": failure("The exporter failed"),
+				},
+			}},
+			"/api/openapi.json": map[string]any{"get": map[string]any{
+				"summary":   "This document",
+				"responses": map[string]any{"200": reply("OpenAPI 3 description of the server", "application/json", &schema{Type: "object"})},
+			}},
+		},
+		"components": map[string]any{"schemas": apiSchemas()},
+	}
+}
+
+// imagePath describes one of the image endpoints
+func imagePath(name, contentType string) map[string]any {
+	text := func(description string) map[string]any {
+		return map[string]any{"description": description, "content": map[string]any{"text/plain": map[string]any{"schema": &schema{Type: "string"}}}}
+	}
+	return map[string]any{"get": map[string]any{
+		"summary": "The art as a cacheable " + name + " image, for <img> tags",
+		"description": fmt.Sprintf("The query is limited to %d bytes, the text to %d characters and the art to %d cells. "+
+			"Images carry an ETag and may be cached for a day.", imageMaxQuery, imageMaxText, imageMaxCells),
+		"parameters": queryParameters(apiSchemas()["ImageQuery"]),
+		"responses": map[string]any{
+			"200": map[string]any{
+				"description": "The image",
+				"headers": map[string]any{
+					"ETag":          map[string]any{"schema": &schema{Type: "string"}},
+					"Cache-Control": map[string]any{"schema": &schema{Type: "string"}},
+				},
+				"content": map[string]any{contentType: map[string]any{"schema": &schema{Type: "string", Format: "binary"}}},
+			},
+			"304": map[string]any{"description": "If-None-Match named the current ETag"},
+			"400": text("Invalid parameters or art too large"),
+			"414": text("Query too long"),
+		},
+	}}
+}
+
+// queryParameters lists the properties of an object schema as OpenAPI query
+// parameters; list properties may repeat
+func queryParameters(s *schema) []map[string]any {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	params := make([]map[string]any, len(names))
+	for i, name := range names {
+		params[i] = map[string]any{
+			"name":     name,
+			"in":       "query",
+			"required": slices.Contains(s.Required, name),
+			"schema":   s.Properties[name],
+		}
+		if s.Properties[name].Type == "array" {
+			params[i]["explode"] = true
+		}
+	}
+	return params
+}
+
+// openAPIHandler serves the OpenAPI document
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeAPIError(w, http.StatusMethodNotAllowed, apiError{Code: "method_not_allowed", Message: "use GET"})
+		return
+	}
+	writeJSON(w, http.StatusOK, openAPIDocument())
+}
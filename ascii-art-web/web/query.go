@@ -0,0 +1,143 @@
+// Renders GET requests for terminals and browsers, choosing the output from
+// the Accept and User-Agent headers
+
+package web
+
+import (
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/export"
+)
+
+// Outputs of a GET render
+const (
+	outputANSI  = "ansi"  // colored text for terminals
+	outputPlain = "plain" // text without escape codes
+	outputHTML  = "html"  // a standalone page
+)
+
+// terminalAgents are the User-Agent prefixes of command-line clients that
+// show ANSI colors
+var terminalAgents = []string{"curl/", "wget/", "httpie/"}
+
+// renderQueryHandler renders GET /ascii-art?text=... in the form the client
+// asked for, so the server can be used straight from a terminal
+func renderQueryHandler(w http.ResponseWriter, r *http.Request) {
+	output := negotiate(r)
+	w.Header().Set("Vary", "Accept, User-Agent")
+
+	query := r.URL.Query()
+	if problems := apiSchemas()["AsciiArtQuery"].checkForm(query); len(problems) > 0 {
+		queryError(w, output, http.StatusBadRequest, html.EscapeString(problems[0].Message))
+		return
+	}
+	p := queryRequest(query)
+	art, err := renderAscii(p)
+	if err != nil {
+		queryError(w, output, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch output {
+	case outputANSI:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, art.ANSI(true))
+	case outputPlain:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, art.Plain())
+	default:
+		e, _ := export.Lookup("html")
+		w.Header().Set("Content-Type", e.ContentType)
+		e.Write(w, art, export.Options{
+			TrueColor: true,
+			Meta:      export.Meta{Text: p.Text, Markup: p.Markup, Banner: p.Banner, Align: p.Align, Width: p.Width},
+		})
+	}
+}
+
+// queryRequest reads render settings from validated query parameters
+func queryRequest(query url.Values) *asciiRequest {
+	width, _ := strconv.Atoi(query.Get("width"))
+	p := &asciiRequest{
+		Text:         strings.ReplaceAll(query.Get("text"), "\r", ""),
+		Banner:       query.Get("banner"),
+		Align:        query.Get("align"),
+		GlobalColor:  query.Get("color"),
+		ColorTargets: query["colorTarget"],
+		TargetColors: query["targetColor"],
+		Gradient:     query.Get("gradient"),
+		GradientDir:  query.Get("gradientDirection"),
+		Markup:       query.Get("markup") != "",
+		Theme:        query.Get("theme"),
+		ThemeBy:      query.Get("themeBy"),
+		Seed:         query.Get("seed"),
+		Width:        width,
+		Wrap:         query.Get("wrap") != "",
+	}
+	if p.Banner == "" {
+		p.Banner = "standard"
+	}
+	return p
+}
+
+// queryError reports a failed GET render as an error page for browsers, or
+// as one line of text for terminals. message is HTML-escaped.
+func queryError(w http.ResponseWriter, output string, status int, message string) {
+	if output == outputHTML {
+		renderErrorWithMessage(w, status, message)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "error: %s\n", html.UnescapeString(message))
+}
+
+// negotiate picks the output of a GET render. An Accept header that prefers
+// text/plain or text/html decides; otherwise command-line clients get ANSI
+// colors and everyone else a page.
+func negotiate(r *http.Request) string {
+	switch preferredType(r.Header.Get("Accept"), "text/plain", "text/html") {
+	case "text/plain":
+		return outputPlain
+	case "text/html":
+		return outputHTML
+	}
+	agent := strings.ToLower(r.UserAgent())
+	for _, prefix := range terminalAgents {
+		if strings.HasPrefix(agent, prefix) {
+			return outputANSI
+		}
+	}
+	return outputHTML
+}
+
+// preferredType returns whichever of the offered media types the Accept
+// header names with the highest quality, or "" when it names none of them
+// (wildcards don't count). Ties go to the type listed first in the header.
+func preferredType(accept string, offers ...string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		for _, offer := range offers {
+			if mediaType == offer && q > bestQ {
+				best, bestQ = offer, q
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,259 @@
+// Checks requests against the schemas published in the OpenAPI document
+
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/canvas"
+	"platform.zone01.gr/git/askordal/ascii-art-core/color"
+)
+
+// schema is the subset of OpenAPI 3 schema objects the server uses. Codes
+// (x-error-codes) names the API error code reported when a keyword fails,
+// e.g. {"enum": "unknown_banner"}; without one a generic code is used.
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Codes                map[string]string  `json:"x-error-codes,omitempty"`
+
+	hint string // message for a pattern mismatch
+}
+
+// intPtr and boolPtr fill the optional schema keywords
+func intPtr(n int) *int    { return &n }
+func boolPtr(b bool) *bool { return &b }
+
+// refPrefix starts the $ref of every named schema
+const refPrefix = "#/components/schemas/"
+
+// ref points to a named schema of the document
+func ref(name string) *schema {
+	return &schema{Ref: refPrefix + name}
+}
+
+// typeNames describes the JSON types in error messages
+var typeNames = map[string]string{
+	"string":  "a string",
+	"integer": "a whole number",
+	"boolean": "true or false",
+	"array":   "a list",
+	"object":  "an object",
+}
+
+// formats checks the string formats the server defines: a color the
+// shared parser understands, or a "fg/bg+attr" style made of them
+var formats = map[string]func(string) error{
+	"color": func(s string) error {
+		_, err := color.Parse(s)
+		return err
+	},
+	"style": checkStyle,
+}
+
+// checkStyle checks a "fg/bg+attr" style, which must set something
+func checkStyle(spec string) error {
+	fg, bg, attrs, err := canvas.SplitStyle(spec)
+	if err != nil {
+		return err
+	}
+	if fg == "" && bg == "" && attrs == 0 {
+		return fmt.Errorf("style %q sets no color or attribute", spec)
+	}
+	for _, code := range []string{fg, bg} {
+		if code == "" {
+			continue
+		}
+		if _, err := color.Parse(code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check validates a decoded JSON value (with numbers as json.Number) and
+// returns every problem found, with the path of the offending field
+func (s *schema) check(field string, v any) []apiError {
+	if s.Ref != "" {
+		return apiSchemas()[strings.TrimPrefix(s.Ref, refPrefix)].check(field, v)
+	}
+	wrongType := []apiError{{Code: "invalid_type", Message: fmt.Sprintf("%s must be %s", field, typeNames[s.Type]), Field: field}}
+	fail := func(keyword, fallback, format string, args ...any) []apiError {
+		code := fallback
+		if c, ok := s.Codes[keyword]; ok {
+			code = c
+		}
+		return []apiError{{Code: code, Message: fmt.Sprintf(format, args...), Field: field}}
+	}
+
+	switch s.Type {
+	case "object":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return wrongType
+		}
+		var problems []apiError
+		for _, name := range s.Required {
+			if _, ok := m[name]; !ok {
+				path := joinField(field, name)
+				problems = append(problems, apiError{Code: "missing_field", Message: path + " is required", Field: path})
+			}
+		}
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			path := joinField(field, name)
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					problems = append(problems, apiError{Code: "unknown_field", Message: "unknown field " + path, Field: path})
+				}
+				continue
+			}
+			problems = append(problems, prop.check(path, m[name])...)
+		}
+		return problems
+
+	case "array":
+		list, ok := v.([]any)
+		if !ok {
+			return wrongType
+		}
+		if s.MaxItems != nil && len(list) > *s.MaxItems {
+			return fail("maxItems", "too_many_items", "%s is limited to %d entries", field, *s.MaxItems)
+		}
+		var problems []apiError
+		for i, item := range list {
+			problems = append(problems, s.Items.check(fmt.Sprintf("%s[%d]", field, i), item)...)
+		}
+		return problems
+
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return wrongType
+		}
+		switch {
+		case s.MinLength != nil && len(str) < *s.MinLength:
+			if *s.MinLength == 1 {
+				return fail("minLength", "too_short", "%s must not be empty", field)
+			}
+			return fail("minLength", "too_short", "%s needs at least %d characters", field, *s.MinLength)
+		case s.MaxLength != nil && len(str) > *s.MaxLength:
+			return fail("maxLength", "too_long", "%s is limited to %d characters", field, *s.MaxLength)
+		case s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(str):
+			if s.hint != "" {
+				return fail("pattern", "invalid_format", "%s: %s", field, s.hint)
+			}
+			return fail("pattern", "invalid_format", "%s must match %s", field, s.Pattern)
+		case len(s.Enum) > 0 && !slices.Contains(s.Enum, str):
+			return fail("enum", "invalid_value", "%s must be one of %s, got %q", field, strings.Join(s.Enum, ", "), str)
+		}
+		if parse, ok := formats[s.Format]; ok && str != "" {
+			if err := parse(str); err != nil {
+				return fail("format", "invalid_format", "%s: %v", field, err)
+			}
+		}
+
+	case "integer":
+		num, ok := v.(json.Number)
+		if !ok {
+			return wrongType
+		}
+		n, err := num.Int64()
+		if err != nil {
+			return wrongType
+		}
+		switch {
+		case s.Minimum != nil && n < int64(*s.Minimum):
+			return fail("minimum", "out_of_range", "%s %s", field, s.rangeText())
+		case s.Maximum != nil && n > int64(*s.Maximum):
+			return fail("maximum", "out_of_range", "%s %s", field, s.rangeText())
+		}
+
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return wrongType
+		}
+	}
+	return nil
+}
+
+// rangeText describes the bounds of an integer schema
+func (s *schema) rangeText() string {
+	switch {
+	case s.Minimum != nil && s.Maximum != nil:
+		return fmt.Sprintf("must be between %d and %d", *s.Minimum, *s.Maximum)
+	case s.Minimum != nil:
+		return fmt.Sprintf("must be at least %d", *s.Minimum)
+	}
+	return fmt.Sprintf("must be at most %d", *s.Maximum)
+}
+
+// checkForm validates form values against an object schema. Fields the
+// schema doesn't list are ignored, and empty values count as missing, the
+// way browsers send untouched inputs.
+func (s *schema) checkForm(form url.Values) []apiError {
+	m := map[string]any{}
+	for name, prop := range s.Properties {
+		values := form[name]
+		switch {
+		case prop.Type == "array":
+			if len(values) > 0 {
+				items := make([]any, len(values))
+				for i, v := range values {
+					items[i] = v
+				}
+				m[name] = items
+			}
+		case len(values) == 0 || values[0] == "":
+		case prop.Type == "integer":
+			m[name] = json.Number(values[0])
+		default:
+			m[name] = values[0]
+		}
+	}
+	return s.check("", m)
+}
+
+// checkRequestForm parses the form of r, URL-encoded or multipart, and
+// validates it against the named schema
+func checkRequestForm(r *http.Request, name string) []apiError {
+	if err := r.ParseMultipartForm(32 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return []apiError{{Code: "invalid_form", Message: "the form could not be read"}}
+	}
+	return apiSchemas()[name].checkForm(r.Form)
+}
+
+// joinField builds the path of a nested field
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,114 @@
+package web
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"platform.zone01.gr/git/askordal/ascii-art-web-export-file/utils"
+)
+
+func TestMain(m *testing.M) {
+	// the schemas list the loaded banners, so load some before they're built
+	LoadedBanners = map[string]utils.BannerType{"standard": {}, "shadow": {}}
+	os.Exit(m.Run())
+}
+
+// problem is the part of an apiError the tests compare
+type problem struct{ code, field string }
+
+func problems(errs []apiError) []problem {
+	var got []problem
+	for _, e := range errs {
+		got = append(got, problem{e.Code, e.Field})
+	}
+	return got
+}
+
+func TestRenderRequestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []problem
+	}{
+		{"valid", `{"text":"Hi","banner":"shadow","width":80,"colors":[{"selector":"H","style":"red/#000+bold"}]}`, nil},
+		{"not an object", `[1]`, []problem{{"invalid_type", ""}}},
+		{"missing text", `{}`, []problem{{"missing_field", "text"}}},
+		{"empty text", `{"text":""}`, []problem{{"missing_field", "text"}}},
+		{"unsupported character", `{"text":"héllo"}`, []problem{{"unsupported_character", "text"}}},
+		{"unknown field", `{"text":"a","size":3}`, []problem{{"unknown_field", "size"}}},
+		{"wrong type", `{"text":"a","markup":"yes"}`, []problem{{"invalid_type", "markup"}}},
+		{"wrap not a boolean", `{"text":"a","wrap":"yes"}`, []problem{{"invalid_type", "wrap"}}},
+		{"unknown banner", `{"text":"a","banner":"comic"}`, []problem{{"unknown_banner", "banner"}}},
+		{"invalid align", `{"text":"a","align":"middle"}`, []problem{{"invalid_align", "align"}}},
+		{"width too small", `{"text":"a","width":0}`, []problem{{"invalid_width", "width"}}},
+		{"width not whole", `{"text":"a","width":1.5}`, []problem{{"invalid_type", "width"}}},
+		{"unknown format", `{"text":"a","format":"bmp"}`, []problem{{"unknown_format", "format"}}},
+		{"bad color", `{"text":"a","colors":[{"style":"nocolor"}]}`, []problem{{"invalid_color", "colors[0].style"}}},
+		{"empty style", `{"text":"a","colors":[{"style":""}]}`, []problem{{"invalid_color", "colors[0].style"}}},
+		{"style without color", `{"text":"a","colors":[{"style":"/"}]}`, []problem{{"invalid_color", "colors[0].style"}}},
+		{"missing style", `{"text":"a","colors":[{"selector":"a"}]}`, []problem{{"missing_field", "colors[0].style"}}},
+		{"every problem reported", `{"banner":"comic","align":"middle"}`,
+			[]problem{{"missing_field", "text"}, {"invalid_align", "align"}, {"unknown_banner", "banner"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc any
+			dec := json.NewDecoder(strings.NewReader(tt.body))
+			dec.UseNumber()
+			if err := dec.Decode(&doc); err != nil {
+				t.Fatal(err)
+			}
+			if got := problems(apiSchemas()["RenderRequest"].check("", doc)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("check(%s) = %v, want %v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderRequestTooManyColors(t *testing.T) {
+	colors := make([]any, apiMaxColors+1)
+	for i := range colors {
+		colors[i] = map[string]any{"style": "red"}
+	}
+	doc := map[string]any{"text": "a", "colors": colors}
+	want := []problem{{"too_many_items", "colors"}}
+	if got := problems(apiSchemas()["RenderRequest"].check("", doc)); !reflect.DeepEqual(got, want) {
+		t.Errorf("check = %v, want %v", got, want)
+	}
+}
+
+func TestCheckFormErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []problem
+	}{
+		{"valid", "inputText=Hi&banner=shadow&align=right&width=80&wrap=on&color=red/%23000%2Bbold&seed=-3", nil},
+		{"empty values count as missing", "inputText=&banner=standard&color=", []problem{{"missing_field", "inputText"}}},
+		{"missing banner", "inputText=a", []problem{{"missing_field", "banner"}}},
+		{"unknown banner", "inputText=a&banner=comic", []problem{{"unknown_banner", "banner"}}},
+		{"invalid align", "inputText=a&banner=standard&align=middle", []problem{{"invalid_value", "align"}}},
+		{"bad color", "inputText=a&banner=standard&color=nocolor", []problem{{"invalid_color", "color"}}},
+		{"bad target color", "inputText=a&banner=standard&colorTarget=a&targetColor=red&targetColor=x", []problem{{"invalid_color", "targetColor[1]"}}},
+		{"width out of range", "inputText=a&banner=standard&width=5000", []problem{{"out_of_range", "width"}}},
+		{"width not a number", "inputText=a&banner=standard&width=wide", []problem{{"invalid_type", "width"}}},
+		{"wrap not a checkbox", "inputText=a&banner=standard&wrap=yes", []problem{{"invalid_value", "wrap"}}},
+		{"seed not a number", "inputText=a&banner=standard&seed=x", []problem{{"invalid_format", "seed"}}},
+		{"unknown fields ignored", "inputText=a&banner=standard&utm_source=x", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := problems(apiSchemas()["AsciiArtForm"].checkForm(form)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkForm(%s) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -8,12 +8,21 @@ import (
 )
 
 func StartServer() {
+	loadBanners()
+	loadErrorTemplate()
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/ascii-art", withRecover(asciiArtHandler))
 	mux.HandleFunc("/ascii-table", withRecover(asciiTableHandler))
 	mux.HandleFunc("/error", withRecover(errorPageHandler))
 	mux.HandleFunc("/", withRecover(indexHandler))
 	mux.HandleFunc("/export", withRecover(handleExport))
+	mux.HandleFunc("/ansi", withRecover(ansiUploadHandler))
+	mux.HandleFunc("/themes", withRecover(themesHandler))
+	mux.HandleFunc("/api/v1/render", withRecover(apiRenderHandler))
+	mux.HandleFunc("/api/openapi.json", withRecover(openAPIHandler))
+	mux.HandleFunc("/render.svg", withRecover(imageHandler("svg")))
+	mux.HandleFunc("/render.png", withRecover(imageHandler("png")))
 
 	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
 
@@ -0,0 +1,35 @@
+// Loads the color theme catalog and serves it to the front end
+
+package web
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"platform.zone01.gr/git/askordal/ascii-art-core/paint"
+)
+
+var LoadedThemes []paint.Theme
+
+func init() {
+	themes, skipped, err := paint.LoadThemes(paint.ThemeDir())
+	if err != nil {
+		log.Fatalf("error loading themes: %v", err)
+	}
+	for _, problem := range skipped {
+		log.Printf("skipped %v", problem)
+	}
+	LoadedThemes = themes
+	log.Printf("Loaded %d themes", len(themes))
+}
+
+// themesHandler returns the theme catalog as JSON for the theme dropdown
+func themesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		renderErrorPage(w, r, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LoadedThemes)
+}